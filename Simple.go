@@ -2,7 +2,10 @@ package command
 
 import (
 	"errors"
+	"reflect"
 	"strings"
+
+	"melato.org/command/reflx"
 )
 
 // Init is an optional interface for flags objects.
@@ -33,7 +36,7 @@ type Configured interface {
 type Closer interface {
 	// Close is called after the command runs, to do any cleanup.
 	//
-	// Close will be called even if Configured() or the command return an error.
+	// Close will be called even if Configured() or the command return an error, or panic.
 	//
 	// It is meant to be used in situations where a Configured() method creates a temporary file, which should be deleted when the program exits.
 	Close() error
@@ -49,9 +52,20 @@ type Closer interface {
 type SimpleCommand struct {
 	subcommands  map[string]*SimpleCommand
 	runMethod    func([]string) error
+	runFunc      interface{} // The function passed to RunFunc(), if any
+	funcUse      string      // The usage created from runFunc
+	services     map[reflect.Type]provider
 	Usage        Usage
 	commandFlags interface{} // The argument that was passed to the Flags() method.  This is meant for internal use.
 	noConfig     bool
+	middleware   []Middleware
+	crashReports bool
+	crashDir     string
+	pluginsOn    bool
+	pluginPrefix string
+	dryRunName   string
+	expandFiles  bool
+	parserMgr    reflx.ParserManager
 }
 
 // A generic representation of the command-line arguments, without any options, e.g. "<arg1> <arg2>"
@@ -86,6 +100,10 @@ func (t *SimpleCommand) Example(example string) *SimpleCommand {
 // flags.Init(), flags.Configured(), or flags.Close() are called as specified in the interface documentation.
 func (t *SimpleCommand) Flags(flags interface{}) *SimpleCommand {
 	t.commandFlags = flags
+	if flags != nil {
+		// run functions may receive the flags
+		registerInjected(reflect.TypeOf(flags))
+	}
 	return t
 }
 
@@ -96,6 +114,7 @@ func (t *SimpleCommand) flags() interface{} {
 // Specify the method to run when executing this command.  The command arguments are passed to the method.
 func (t *SimpleCommand) RunMethodArgs(method func([]string) error) *SimpleCommand {
 	t.runMethod = method
+	t.runFunc = nil
 	t.Usage.Use = "arg..."
 	return t
 }
@@ -122,10 +141,13 @@ func (t *SimpleCommand) RunMethod(method func()) *SimpleCommand {
 // It is like the RunMethod* methods, but it uses reflection to match the function arguments to the provided arguments.
 // The command arguments are passed to the function.
 // fn must return either 0 values or one value that is assignable to error
-// It may have any number of arguments of any primitive type (that can be parsed from a string)
+// It may have any number of arguments of any type that has a parser, such as the primitive types.
+// Parameters of injected types, such as context.Context, are injected.  See Inject and Provide.
 func (t *SimpleCommand) RunFunc(fn interface{}) *SimpleCommand {
 	t.RunMethodArgs(wrapFunc(fn))
-	t.Usage.Use = funcUsage(fn)
+	t.runFunc = fn
+	t.funcUse = funcUsage(fn)
+	t.Usage.Use = t.funcUse
 	return t
 }
 
@@ -151,7 +173,10 @@ func (t *SimpleCommand) Command(name string) *SimpleCommand {
 	return c
 }
 
-func (t *SimpleCommand) run(args []string) error {
+func (t *SimpleCommand) run(args []string, pm reflx.ParserManager, inj *injector) error {
+	if t.runFunc != nil {
+		return invokeFunc(t.runFunc, args, pm, inj)
+	}
 	if t.runMethod != nil {
 		return t.runMethod(args)
 	}
@@ -196,6 +221,133 @@ func (t *SimpleCommand) cleanup() error {
 	return nil
 }
 
+// Wrap adds middleware that wraps the execution of this command and of any descendant command.
+//
+// Middleware is composed from the root command to the command that runs,
+// so the middleware of the root command is the outermost.
+// It wraps the calls to Configured() and the run method.
+// Close() is called after the middleware returns, even if it does not call next.
+func (t *SimpleCommand) Wrap(m Middleware) *SimpleCommand {
+	t.middleware = append(t.middleware, m)
+	return t
+}
+
+func (t *SimpleCommand) wrappers() []Middleware {
+	return t.middleware
+}
+
+// CrashReports enables writing a crash report file when this command or any descendant command panics.
+// The report contains the command path, the arguments, the panic value and the stack trace.
+// dir is the directory where reports are written.  If it is empty, os.TempDir() is used.
+//
+// Panics are always recovered and returned as a *PanicError, whether crash reports are enabled or not.
+// Main prints the stack trace of a *PanicError to stderr, unless it was written to a crash report.
+func (t *SimpleCommand) CrashReports(dir string) *SimpleCommand {
+	t.crashReports = true
+	t.crashDir = dir
+	return t
+}
+
+func (t *SimpleCommand) crashReportDir() (string, bool) {
+	return t.crashDir, t.crashReports
+}
+
+// Plugins enables external plugin subcommands, which are executables found in the PATH.
+// When the first argument after the flags of this command is not a built-in subcommand,
+// the executable "<prefix>-<name>" is run with the remaining arguments, the environment and standard input and output of the program.
+// The program exits with the exit code of the plugin, or with 128 + the signal number, if the plugin is killed by a signal.
+// The plugin runs like a subcommand, within the middleware of the command chain, after the Configured() methods and before the Close() methods.
+// If this command has a run method, it runs when the first argument is not a subcommand or a plugin.
+//
+// If prefix is empty, the command path is used, joined with "-", e.g. "mytool" for the root command, or "mytool-sub" for a subcommand.
+//
+// Plugins found in the PATH are listed in the available commands.
+func (t *SimpleCommand) Plugins(prefix string) *SimpleCommand {
+	t.pluginsOn = true
+	t.pluginPrefix = prefix
+	return t
+}
+
+func (t *SimpleCommand) plugins() (string, bool) {
+	return t.pluginPrefix, t.pluginsOn
+}
+
+// DryRunFlag adds a boolean flag with the given name to this command.
+// When the flag is set, this command or any descendant command is not run.
+// Instead, its flags are parsed and validated by calling the Configured() methods,
+// and then the resolved command path, the value and source of every flag, and the decoded command arguments are printed as JSON.
+// It is an error if the command has another flag with the same name.
+func (t *SimpleCommand) DryRunFlag(name string) *SimpleCommand {
+	t.dryRunName = name
+	return t
+}
+
+func (t *SimpleCommand) dryRunFlag() string {
+	return t.dryRunName
+}
+
+// ResponseFiles enables response files, for commands whose arguments may exceed command-line length limits.
+// It applies only to the root command.
+//
+// Each "@file" argument is replaced by the arguments read from the file, before parsing any flags.
+// The file is split into arguments using shell-like quoting rules, and a # at the start of an argument starts a comment, up to the end of the line.
+// Response files may contain other "@file" arguments, but a file may not include itself, directly or indirectly.
+// "@-" reads arguments from the standard input.
+// "@@arg" is passed as "@arg".
+func (t *SimpleCommand) ResponseFiles() *SimpleCommand {
+	t.expandFiles = true
+	return t
+}
+
+func (t *SimpleCommand) responseFiles() bool {
+	return t.expandFiles
+}
+
+func (t *SimpleCommand) decodeArgs(args []string, pm reflx.ParserManager) ([]reflect.Value, error) {
+	if t.runFunc != nil {
+		fType := reflect.TypeOf(t.runFunc)
+		return parseArgs(fType, argIndexes(fType), args, pm)
+	}
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		values[i] = reflect.ValueOf(arg)
+	}
+	return values, nil
+}
+
+// Parsers specifies the parsers that convert strings to values, for the flags and the run function arguments
+// of this command and its descendant commands, unless a descendant command specifies its own parsers.
+// It is typically called for the root command, in order to add parsers for application types.
+// Commands that have no parsers use DefaultParsers.
+//
+// FlagInfo() uses only the parsers of its own command, or DefaultParsers, since it does not know the ancestors of the command.
+// AncestorFlagInfo() also uses the parsers of the ancestors.
+func (t *SimpleCommand) Parsers(pm reflx.ParserManager) *SimpleCommand {
+	t.parserMgr = pm
+	return t
+}
+
+func (t *SimpleCommand) parsers() reflx.ParserManager {
+	return t.parserMgr
+}
+
+func (t *SimpleCommand) provide(vType reflect.Type, p provider) *SimpleCommand {
+	if t.services == nil {
+		t.services = make(map[reflect.Type]provider)
+	}
+	t.services[vType] = p
+	return t
+}
+
+func (t *SimpleCommand) providers() map[reflect.Type]provider {
+	return t.services
+}
+
 func (t *SimpleCommand) usage() *Usage {
+	if t.runFunc != nil && t.Usage.Use == t.funcUse {
+		// types may have been registered with Inject after RunFunc()
+		t.funcUse = funcUsage(t.runFunc)
+		t.Usage.Use = t.funcUse
+	}
 	return &t.Usage
 }
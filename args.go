@@ -0,0 +1,156 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// splitArgs splits text into arguments, using shell-like quoting rules:
+//   - arguments are separated by white space, including newlines
+//   - single quotes preserve the literal value of the enclosed characters
+//   - double quotes preserve the enclosed characters, except that a backslash escapes \, " and newline
+//   - outside quotes, a backslash escapes the next character, and a backslash-newline is removed
+//   - a # at the start of an argument starts a comment, which extends to the end of the line
+func splitArgs(text string) ([]string, error) {
+	var args []string
+	var buf strings.Builder
+	inArg := false
+	runes := []rune(text)
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+		case c == '#' && !inArg:
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+		case c == '\\':
+			i++
+			if i >= n {
+				return nil, errors.New("trailing backslash")
+			}
+			if runes[i] != '\n' {
+				buf.WriteRune(runes[i])
+				inArg = true
+			}
+		case c == '\'':
+			inArg = true
+			end := i + 1
+			for end < n && runes[end] != '\'' {
+				end++
+			}
+			if end >= n {
+				return nil, errors.New("unterminated single quote")
+			}
+			buf.WriteString(string(runes[i+1 : end]))
+			i = end
+		case c == '"':
+			inArg = true
+			i++
+			for ; i < n && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < n {
+					switch runes[i+1] {
+					case '\\', '"':
+						i++
+					case '\n':
+						i++
+						continue
+					}
+				}
+				buf.WriteRune(runes[i])
+			}
+			if i >= n {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			buf.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
+
+// argExpander expands response file arguments.
+type argExpander struct {
+	stdin io.Reader
+	// files contains the response files that are being expanded, to detect cycles
+	files []string
+}
+
+// expand replaces each "@file" argument with the arguments read from the file, recursively.
+// "@-" reads arguments from the standard input.
+// "@@arg" is replaced by "@arg", without reading any file.
+func (t *argExpander) expand(args []string) ([]string, error) {
+	var result []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") || arg == "@" {
+			result = append(result, arg)
+			continue
+		}
+		if strings.HasPrefix(arg, "@@") {
+			result = append(result, arg[1:])
+			continue
+		}
+		fileArgs, err := t.expandFile(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fileArgs...)
+	}
+	return result, nil
+}
+
+func (t *argExpander) expandFile(file string) ([]string, error) {
+	key := file
+	if file != "-" {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		key = abs
+	}
+	for _, f := range t.files {
+		if f == key {
+			return nil, fmt.Errorf("response file cycle: %s", strings.Join(append(t.files, key), " -> "))
+		}
+	}
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(t.stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	args, err := splitArgs(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("@%s: %w", file, err)
+	}
+	t.files = append(t.files, key)
+	defer func() {
+		t.files = t.files[:len(t.files)-1]
+	}()
+	return t.expand(args)
+}
+
+// expandArgs expands response file arguments.  See argExpander.expand
+func expandArgs(args []string) ([]string, error) {
+	expander := &argExpander{stdin: os.Stdin}
+	return expander.expand(args)
+}
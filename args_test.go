@@ -0,0 +1,55 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func verifySplitArgs(t *testing.T, text string, expected ...string) {
+	args, err := splitArgs(text)
+	if err != nil {
+		t.Errorf("%s: %v", text, err)
+		return
+	}
+	if strings.Join(args, "|") != strings.Join(expected, "|") || len(args) != len(expected) {
+		t.Errorf("%s -> %q, expected %q", text, args, expected)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	verifySplitArgs(t, "")
+	verifySplitArgs(t, "a b\n c", "a", "b", "c")
+	verifySplitArgs(t, `'a b' "c \"d\"" e\ f`, "a b", `c "d"`, "e f")
+	verifySplitArgs(t, "# comment\na # another\nb#c", "a", "b#c")
+	verifySplitArgs(t, `'' ""`, "", "")
+	verifySplitArgs(t, "a\\\nb", "ab")
+	for _, text := range []string{`'a`, `"a`, `a\`} {
+		if _, err := splitArgs(text); err == nil {
+			t.Errorf("%s: expected error", text)
+		}
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("-x 1 @"+b+"\n"), 0644)
+	os.WriteFile(b, []byte("'y z'"), 0644)
+	expander := &argExpander{stdin: strings.NewReader("s1 s2")}
+	args, err := expander.expand([]string{"@" + a, "@-", "@@c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "-x|1|y z|s1|s2|@c"
+	if strings.Join(args, "|") != expected {
+		t.Errorf("%q", args)
+	}
+	os.WriteFile(b, []byte("@"+a), 0644)
+	_, err = expander.expand([]string{"@" + a})
+	if err == nil {
+		t.Errorf("expected cycle error")
+	}
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type benchFlags struct {
+	Name    string   `name:"name,n" usage:"name"`
+	Count   int      `usage:"count"`
+	Verbose bool     `usage:"verbose"`
+	Tags    []string `name:"tag" usage:"tags"`
+	Sub     benchSub `name:"sub" usage:"sub:"`
+	Ptr     *benchSub
+}
+
+type benchRootFlags struct {
+	Debug  bool   `usage:"debug"`
+	Config string `usage:"config file"`
+}
+
+type benchSub struct {
+	X string  `usage:"x"`
+	Y float64 `usage:"y"`
+}
+
+// benchTree creates a command tree with n leaf commands, in groups of 100.
+func benchTree(n int) *SimpleCommand {
+	root := &SimpleCommand{}
+	root.Short("root").Flags(&benchRootFlags{})
+	var group *SimpleCommand
+	for i := 0; i < n; i++ {
+		if i%100 == 0 {
+			group = root.Command(fmt.Sprintf("group%02d", i/100)).Short("group")
+		}
+		flags := &benchFlags{Ptr: &benchSub{}}
+		group.Command(fmt.Sprintf("cmd%04d", i)).Short("command").Flags(flags).RunFunc(func(n int, args ...string) {})
+	}
+	return root
+}
+
+func clearMetaCache() {
+	metaLock.Lock()
+	metaCache = make(map[metaKey][]*fieldMeta)
+	metaLock.Unlock()
+}
+
+func BenchmarkBuildTree(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchTree(1000)
+	}
+}
+
+func BenchmarkRunCommand(b *testing.B) {
+	root := benchTree(1000)
+	args := []string{"-debug", "group05", "cmd0500", "-sub.x", "x", "-tag", "t1", "1", "s"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := runCommand("app", root, args, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractFlags(b *testing.B) {
+	flags := &benchFlags{Ptr: &benchSub{}}
+	for i := 0; i < b.N; i++ {
+		if _, err := extractFlags(flags, &flagPrefix{}, DefaultParsers); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractFlagsUncached(b *testing.B) {
+	flags := &benchFlags{Ptr: &benchSub{}}
+	for i := 0; i < b.N; i++ {
+		clearMetaCache()
+		if _, err := extractFlags(flags, &flagPrefix{}, DefaultParsers); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	root := benchTree(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := root.Validate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHelp(b *testing.B) {
+	root := benchTree(1000)
+	group := root.Commands()["group05"]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var levels []*commandInfo
+		for _, c := range []struct {
+			name string
+			cmd  *SimpleCommand
+		}{{"app", root}, {"group05", group}} {
+			ci := createCommandInfo(c.name, c.cmd)
+			inheritParsers(ci, levels)
+			fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+			if err := ci.setFlags(fs); err != nil {
+				b.Fatal(err)
+			}
+			ci.FlagSet = fs
+			levels = append(levels, ci)
+		}
+		showUsage(io.Discard, levels, group.Commands())
+	}
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"melato.org/command/internal/util"
+)
+
+const commandPath = "melato.org/command"
+
+// methods that are never used as run functions, as in SimpleCommand.Methods()
+var lifecycleMethods = map[string]bool{
+	"Init":         true,
+	"Configured":   true,
+	"Close":        true,
+	"Usage":        true,
+	"CommandFlags": true,
+}
+
+type generator struct {
+	warn    io.Writer
+	pkg     *types.Package
+	imports map[string]string // import path -> package name
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) warnf(format string, args ...interface{}) {
+	fmt.Fprintf(g.warn, "command-gen: "+format+"\n", args...)
+}
+
+// load parses and type-checks the package in dir, excluding the output file.
+// Type errors are ignored, since the package may refer to previously generated code.
+func (g *generator) load(dir, outFile string) error {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == outFile {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(err error) {},
+	}
+	g.pkg, _ = conf.Check(bp.ImportPath, fset, files, nil)
+	if g.pkg == nil {
+		return fmt.Errorf("cannot type-check %s", dir)
+	}
+	return nil
+}
+
+// generate returns the formatted source code of the generated file.
+func (g *generator) generate(dir, outFile string, typeNames []string) ([]byte, error) {
+	err := g.load(dir, outFile)
+	if err != nil {
+		return nil, err
+	}
+	g.imports = make(map[string]string)
+	for _, name := range typeNames {
+		obj := g.pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a named type", name)
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a struct", name)
+		}
+		err := g.genFlags(name, st)
+		if err != nil {
+			return nil, err
+		}
+		g.genMethods(name, named)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by command-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", g.pkg.Name())
+	var paths []string
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	fmt.Fprintf(&out, "import (\n")
+	// standard packages first
+	for _, std := range []bool{true, false} {
+		for _, path := range paths {
+			if isStandard(path) == std {
+				fmt.Fprintf(&out, "\t%q\n", path)
+			}
+		}
+		fmt.Fprintf(&out, "\n")
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func isStandard(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+// use adds an import and returns the package name.
+func (g *generator) use(path, name string) string {
+	g.imports[path] = name
+	return name
+}
+
+// typeExpr returns the Go expression of a type, adding any imports that it needs.
+func (g *generator) typeExpr(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		return g.use(p.Path(), p.Name())
+	})
+}
+
+// prefix composes the names and usage of the flags of nested structs, like flagPrefix in the command package.
+type prefix struct {
+	Name  string
+	Usage string
+}
+
+func (t prefix) ComposeName(name string) string {
+	if t.Name == "" {
+		return name
+	}
+	return t.Name + "." + name
+}
+
+func (t prefix) ComposeUsage(usage string) string {
+	if t.Usage == "" {
+		return usage
+	} else if usage == "" {
+		return t.Usage
+	} else {
+		return t.Usage + " " + usage
+	}
+}
+
+func (t prefix) Append(p prefix) prefix {
+	if p.Name == "" {
+		return t
+	}
+	return prefix{Name: t.ComposeName(p.Name), Usage: t.ComposeUsage(p.Usage)}
+}
+
+func (g *generator) genFlags(typeName string, st *types.Struct) error {
+	g.use(commandPath, "command")
+	g.printf("\n// CommandFlags returns the flags of %s, without reflection.\n", typeName)
+	g.printf("func (t *%s) CommandFlags() []*command.StaticFlag {\n", typeName)
+	g.printf("var flags []*command.StaticFlag\n")
+	err := g.genStruct(st, "t.", "", prefix{}, []*types.Struct{st}, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", typeName, err)
+	}
+	g.printf("return flags\n}\n")
+	g.printf("\nvar _ command.StaticFlags = (*%s)(nil)\n", typeName)
+	return nil
+}
+
+// genStruct generates the flags of the fields of a struct.
+// expr is the expression of the struct, followed by a dot.
+// stack contains the enclosing structs, to detect recursive types.
+// allocated contains the expressions of the enclosing struct pointers with an alloc:"optional" tag.
+func (g *generator) genStruct(st *types.Struct, expr string, path string, pre prefix, stack []*types.Struct, allocated []string) error {
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		if !field.Exported() {
+			continue
+		}
+		if _, isCommand := tag.Lookup("cmd"); isCommand {
+			continue
+		}
+		nameStr := tag.Get("name")
+		if nameStr == "-" || nameStr == "" {
+			if _, exists := tag.Lookup("name"); exists {
+				continue
+			}
+		}
+		var names []string
+		if nameStr != "" {
+			names = strings.Split(nameStr, ",")
+		}
+		fPrefix := prefix{Usage: tag.Get("usage")}
+		if len(names) == 0 {
+			names = append(names, util.FlagName(field.Name()))
+		} else {
+			fPrefix.Name = names[0]
+		}
+		fPath := field.Name()
+		if path != "" {
+			fPath = path + "." + field.Name()
+		}
+		if _, hasParser := tag.Lookup("parser"); hasParser {
+			return fmt.Errorf("%s: parser tags are not supported", fPath)
+		}
+		fExpr := expr + field.Name()
+		alloc := tag.Get("alloc")
+		switch alloc {
+		case "", "false":
+			alloc = ""
+		case "true", "optional":
+			if p, ok := field.Type().(*types.Pointer); !ok || !isStruct(p.Elem()) {
+				return fmt.Errorf("%s: alloc tag requires a pointer to a struct", fPath)
+			}
+		default:
+			return fmt.Errorf("%s: invalid alloc tag: %s", fPath, alloc)
+		}
+		switch u := field.Type().Underlying().(type) {
+		case *types.Struct:
+			if err := checkRecursion(u, stack, fPath); err != nil {
+				return err
+			}
+			err := g.genStruct(u, fExpr+".", fPath, pre.Append(fPrefix), append(stack, u), allocated)
+			if err != nil {
+				return err
+			}
+		case *types.Pointer:
+			sp, ok := u.Elem().Underlying().(*types.Struct)
+			if !ok {
+				if kindOf(u.Elem()) == "" || tag.Get("count") == "true" {
+					return fmt.Errorf("%s: unsupported type %s", fPath, field.Type())
+				}
+				g.genFlag(names, pre, tag, fPath, fExpr, u.Elem(), optionalFlag, allocated)
+				continue
+			}
+			if err := checkRecursion(sp, stack, fPath); err != nil {
+				return err
+			}
+			if alloc != "" {
+				g.printf("if %s == nil {\n%s = new(%s)\n}\n", fExpr, fExpr, g.typeExpr(u.Elem()))
+			}
+			spAllocated := allocated
+			if alloc == "optional" {
+				spAllocated = append(append([]string(nil), allocated...), "&"+fExpr)
+			}
+			g.printf("if %s != nil {\n", fExpr)
+			err := g.genStruct(sp, fExpr+".", fPath, pre.Append(fPrefix), append(stack, sp), spAllocated)
+			if err != nil {
+				return err
+			}
+			g.printf("}\n")
+		case *types.Interface:
+			return fmt.Errorf("%s: interface fields are not supported", fPath)
+		case *types.Map:
+			if tag.Get("count") == "true" {
+				return fmt.Errorf("%s: count tag requires an integer field", fPath)
+			}
+			if kindOf(u.Key()) == "" || kindOf(u.Elem()) == "" {
+				g.warnf("%s: no parser for %s", fPath, field.Type())
+				continue
+			}
+			g.genFlag(names, pre, tag, fPath, fExpr, field.Type(), mapFlag, allocated)
+		case *types.Slice:
+			if tag.Get("count") == "true" {
+				return fmt.Errorf("%s: count tag requires an integer field", fPath)
+			}
+			if kindOf(u.Elem()) == "" {
+				g.warnf("%s: no parser for %s", fPath, field.Type())
+				continue
+			}
+			g.genFlag(names, pre, tag, fPath, fExpr, u.Elem(), sliceFlag, allocated)
+		default:
+			if tag.Get("count") == "true" {
+				if kind := kindOf(field.Type()); kind != "int" && kind != "uint" {
+					return fmt.Errorf("%s: count tag requires an integer field", fPath)
+				}
+				g.genFlag(names, pre, tag, fPath, fExpr, field.Type(), counterFlag, allocated)
+				continue
+			}
+			if kindOf(field.Type()) == "" {
+				g.warnf("%s: no parser for %s", fPath, field.Type())
+				continue
+			}
+			g.genFlag(names, pre, tag, fPath, fExpr, field.Type(), scalarFlag, allocated)
+		}
+	}
+	return nil
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func checkRecursion(st *types.Struct, stack []*types.Struct, path string) error {
+	for _, s := range stack {
+		if s == st {
+			return fmt.Errorf("%s: recursive types are not supported", path)
+		}
+	}
+	return nil
+}
+
+// flagMode specifies how a flag sets its field
+type flagMode int
+
+const (
+	scalarFlag   flagMode = iota // sets the field
+	sliceFlag                    // appends to a slice field
+	counterFlag                  // increments an integer field
+	optionalFlag                 // allocates a pointer field
+	mapFlag                      // adds key=value entries to a map field
+)
+
+// genFlag generates a single flag.
+// pType is the type that is parsed: the field type, or the element type of a slice or pointer.
+// For a map, it is the map type.
+// allocated contains the expressions of the enclosing struct pointers with an alloc:"optional" tag.
+func (g *generator) genFlag(names []string, pre prefix, tag reflect.StructTag, path, expr string, pType types.Type, mode flagMode, allocated []string) {
+	fmtPkg := g.use("fmt", "fmt")
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	g.printf("{\n")
+	g.printf("p := &%s\n", expr)
+	g.printf("flags = append(flags, &command.StaticFlag{\n")
+	g.printf("Names: []string{%s},\n", strings.Join(quoted, ", "))
+	if pre.Name != "" {
+		g.printf("Prefix: %q,\n", pre.Name)
+	}
+	if usage := pre.ComposeUsage(tag.Get("usage")); usage != "" {
+		g.printf("Usage: %q,\n", usage)
+	}
+	if env := tag.Get("env"); env != "" {
+		g.printf("Env: %q,\n", env)
+	}
+	g.printf("Path: %q,\n", path)
+	g.printf("Field: p,\n")
+	if len(allocated) > 0 {
+		g.printf("Allocated: []interface{}{%s},\n", strings.Join(allocated, ", "))
+	}
+	if initValue := tag.Get("init"); initValue != "" {
+		g.printf("Init: %q,\n", initValue)
+	}
+	g.printf("Value: &command.StaticValue{\n")
+	if mode == optionalFlag {
+		g.printf("Get: func() string {\nif *p == nil {\nreturn \"\"\n}\nreturn %s.Sprint(**p)\n},\n", fmtPkg)
+	} else {
+		g.printf("Get: func() string { return %s.Sprint(*p) },\n", fmtPkg)
+	}
+	g.printf("SetFunc: func(s string) error {\n")
+	var value string
+	if mode == mapFlag {
+		m := pType.Underlying().(*types.Map)
+		g.printf("k, v, err := command.SplitKeyValue(s)\nif err != nil {\nreturn err\n}\n")
+		key := g.parse("k", m.Key())
+		value = g.parse("v", m.Elem())
+		g.printf("(*p)[%s] = %s\n", key, value)
+	} else if mode == counterFlag {
+		g.printf("sV, err := command.ParseCount(s, int64(*p))\nif err != nil {\nreturn err\n}\n")
+		value = g.typeExpr(pType) + "(sV)"
+	} else {
+		value = g.parse("s", pType)
+	}
+	switch mode {
+	case mapFlag:
+	case sliceFlag:
+		g.printf("*p = append(*p, %s)\n", value)
+	case optionalFlag:
+		g.printf("v := %s\n*p = &v\n", value)
+	default:
+		g.printf("*p = %s\n", value)
+	}
+	g.printf("return nil\n},\n")
+	switch mode {
+	case mapFlag:
+		g.printf("Reset: func() { *p = make(%s) },\n", g.typeExpr(pType))
+	case sliceFlag:
+		g.printf("Reset: func() { *p = (*p)[:0] },\n")
+	case optionalFlag:
+		g.printf("Optional: true,\n")
+	}
+	if mode == counterFlag {
+		g.printf("Bool: true,\nCounter: true,\n")
+	} else if types.Identical(pType, types.Typ[types.Bool]) {
+		g.printf("Bool: true,\n")
+	}
+	if types.Identical(pType, types.Typ[types.String]) && mode != sliceFlag && mode != mapFlag {
+		g.printf("Quote: true,\n")
+	}
+	if defaultUse := tag.Get("default"); defaultUse != "" {
+		g.printf("DefaultUse: %q,\n", defaultUse)
+	}
+	g.printf("},\n")
+	g.printf("})\n")
+	g.printf("}\n")
+}
+
+// kindOf returns the kind of a type that has a parser in the default parsers, or "".
+func kindOf(t types.Type) string {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch b.Kind() {
+	case types.String:
+		return "string"
+	case types.Bool:
+		return "bool"
+	case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		return "int"
+	case types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return "uint"
+	case types.Float32, types.Float64:
+		return "float"
+	case types.Complex64, types.Complex128:
+		return "complex"
+	}
+	return ""
+}
+
+// parse generates code that parses the string variable s as type t, returning any error,
+// and returns the expression of the parsed value.
+// It uses the same conversions as the default parsers in package reflx.
+func (g *generator) parse(s string, t types.Type) string {
+	kind := kindOf(t)
+	if kind == "string" {
+		if types.Identical(t, types.Typ[types.String]) {
+			return s
+		}
+		return g.typeExpr(t) + "(" + s + ")"
+	}
+	v := s + "V"
+	strconvPkg := g.use("strconv", "strconv")
+	switch kind {
+	case "bool":
+		g.printf("%s, err := %s.ParseBool(%s)\n", v, strconvPkg, s)
+	case "int":
+		g.printf("%s, err := %s.ParseInt(%s, 10, 64)\n", v, strconvPkg, s)
+	case "uint":
+		g.printf("%s, err := %s.ParseUint(%s, 10, 64)\n", v, strconvPkg, s)
+	case "float":
+		g.printf("%s, err := %s.ParseFloat(%s, 64)\n", v, strconvPkg, s)
+	case "complex":
+		g.printf("%s, err := %s.ParseComplex(%s, 64)\n", v, strconvPkg, s)
+	}
+	g.printf("if err != nil {\nreturn err\n}\n")
+	switch {
+	case types.Identical(t, types.Typ[types.Bool]),
+		types.Identical(t, types.Typ[types.Int64]),
+		types.Identical(t, types.Typ[types.Uint64]),
+		types.Identical(t, types.Typ[types.Float64]),
+		types.Identical(t, types.Typ[types.Complex128]):
+		return v
+	}
+	return g.typeExpr(t) + "(" + v + ")"
+}
+
+// genMethods generates argument conversion methods for the methods of a type.
+func (g *generator) genMethods(typeName string, named *types.Named) {
+	existing := make(map[string]bool)
+	mset := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < mset.Len(); i++ {
+		existing[mset.At(i).Obj().Name()] = true
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		m := named.Method(i)
+		if !m.Exported() || lifecycleMethods[m.Name()] {
+			continue
+		}
+		sig := m.Type().(*types.Signature)
+		if !isRunSignature(sig) {
+			continue
+		}
+		if err := checkParams(sig); err != nil {
+			g.warnf("%s.%s: %v", typeName, m.Name(), err)
+			continue
+		}
+		wrapper := m.Name() + "Args"
+		if existing[wrapper] {
+			g.warnf("%s.%s: %s already exists", typeName, m.Name(), wrapper)
+			continue
+		}
+		g.genMethod(typeName, wrapper, m.Name(), sig)
+	}
+}
+
+// isRunSignature checks that a function returns nothing or an error.
+func isRunSignature(sig *types.Signature) bool {
+	switch sig.Results().Len() {
+	case 0:
+		return true
+	case 1:
+		return types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+	default:
+		return false
+	}
+}
+
+// isStrings checks for a single []string argument, which receives the arguments without conversion.
+func isStrings(sig *types.Signature) bool {
+	if sig.Params().Len() != 1 || sig.Variadic() {
+		return false
+	}
+	return types.Identical(sig.Params().At(0).Type(), types.NewSlice(types.Typ[types.String]))
+}
+
+func checkParams(sig *types.Signature) error {
+	if isStrings(sig) {
+		return nil
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		t := params.At(i).Type()
+		if sig.Variadic() && i == params.Len()-1 {
+			t = t.(*types.Slice).Elem()
+		}
+		if kindOf(t) == "" {
+			return fmt.Errorf("unsupported argument type %s", t)
+		}
+	}
+	return nil
+}
+
+// argTypeName returns the name of an argument type in usage, as in the command package.
+func (g *generator) argTypeName(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		return p.Name()
+	})
+}
+
+func (g *generator) genMethod(typeName, wrapper, method string, sig *types.Signature) {
+	params := sig.Params()
+	n := params.Len()
+	use := make([]string, n)
+	for i := range use {
+		use[i] = "<" + g.argTypeName(params.At(i).Type()) + ">"
+	}
+	g.printf("\n// %s calls %s with the command arguments, without reflection.\n", wrapper, method)
+	if n > 0 {
+		g.printf("// Its usage is %q.\n", strings.Join(use, " "))
+	}
+	g.printf("func (t *%s) %s(args []string) error {\n", typeName, wrapper)
+	var in []string
+	switch {
+	case isStrings(sig):
+		in = append(in, "args")
+	case n == 0:
+		g.printf("if len(args) > 0 {\nreturn %s.New(\"function takes no arguments\")\n}\n", g.use("errors", "errors"))
+	case !sig.Variadic():
+		g.printf("if len(args) != %d {\nreturn %s.New(\"wrong number of arguments\")\n}\n", n, g.use("errors", "errors"))
+	default:
+		g.printf("if len(args) < %d {\nreturn %s.New(\"not enough arguments\")\n}\n", n-1, g.use("errors", "errors"))
+	}
+	if !isStrings(sig) {
+		fixed := n
+		if sig.Variadic() {
+			fixed = n - 1
+		}
+		for i := 0; i < fixed; i++ {
+			a := fmt.Sprintf("a%d", i)
+			g.printf("%s := args[%d]\n", a, i)
+			in = append(in, g.parse(a, params.At(i).Type()))
+		}
+		if sig.Variadic() {
+			elem := params.At(n - 1).Type().(*types.Slice).Elem()
+			g.printf("rest := make([]%s, 0, len(args)-%d)\n", g.typeExpr(elem), fixed)
+			g.printf("for _, arg := range args[%d:] {\n", fixed)
+			g.printf("rest = append(rest, %s)\n", g.parse("arg", elem))
+			g.printf("}\n")
+			in = append(in, "rest...")
+		}
+	}
+	call := fmt.Sprintf("t.%s(%s)", method, strings.Join(in, ", "))
+	if sig.Results().Len() == 1 {
+		g.printf("return %s\n}\n", call)
+	} else {
+		g.printf("%s\nreturn nil\n}\n", call)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGenerate checks that the generated code of the sample package is up to date.
+func TestGenerate(t *testing.T) {
+	var warn bytes.Buffer
+	g := &generator{warn: &warn}
+	src, err := g.generate("internal/sample", "command_gen.go", []string{"Flags", "Funcs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := os.ReadFile("internal/sample/command_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, existing) {
+		t.Errorf("internal/sample/command_gen.go is not up to date.  Run go generate.")
+	}
+	if warn.String() != "command-gen: Funcs.Ignored: unsupported argument type map[string]int\n" {
+		t.Errorf("warnings: %s", warn.String())
+	}
+}
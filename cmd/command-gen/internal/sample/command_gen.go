@@ -0,0 +1,513 @@
+// Code generated by command-gen. DO NOT EDIT.
+
+package sample
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"melato.org/command"
+)
+
+// CommandFlags returns the flags of Flags, without reflection.
+func (t *Flags) CommandFlags() []*command.StaticFlag {
+	var flags []*command.StaticFlag
+	{
+		p := &t.Name
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"n", "name"},
+			Usage: "the name",
+			Env:   "SAMPLE_NAME",
+			Path:  "Name",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					*p = s
+					return nil
+				},
+				Quote: true,
+			},
+		})
+	}
+	{
+		p := &t.Verbose
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"V"},
+			Usage: "verbose output",
+			Path:  "Verbose",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseBool(s)
+					if err != nil {
+						return err
+					}
+					*p = sV
+					return nil
+				},
+				Bool: true,
+			},
+		})
+	}
+	{
+		p := &t.Debug
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"d"},
+			Usage: "debug level",
+			Path:  "Debug",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := command.ParseCount(s, int64(*p))
+					if err != nil {
+						return err
+					}
+					*p = int(sV)
+					return nil
+				},
+				Bool:    true,
+				Counter: true,
+			},
+		})
+	}
+	{
+		p := &t.Count
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"count"},
+			Usage: "number of times",
+			Path:  "Count",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseUint(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					*p = uint8(sV)
+					return nil
+				},
+			},
+		})
+	}
+	{
+		p := &t.Ratio
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"ratio"},
+			Usage: "a ratio",
+			Path:  "Ratio",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseFloat(s, 64)
+					if err != nil {
+						return err
+					}
+					*p = float32(sV)
+					return nil
+				},
+			},
+		})
+	}
+	{
+		p := &t.Level
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"level"},
+			Usage: "the level",
+			Path:  "Level",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					*p = Level(sV)
+					return nil
+				},
+			},
+		})
+	}
+	{
+		p := &t.Timeout
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"timeout"},
+			Usage: "timeout, in nanoseconds",
+			Path:  "Timeout",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					*p = time.Duration(sV)
+					return nil
+				},
+			},
+		})
+	}
+	{
+		p := &t.Tags
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"tag"},
+			Usage: "a tag",
+			Path:  "Tags",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					*p = append(*p, s)
+					return nil
+				},
+				Reset:      func() { *p = (*p)[:0] },
+				DefaultUse: "none",
+			},
+		})
+	}
+	{
+		p := &t.Server.Host
+		flags = append(flags, &command.StaticFlag{
+			Names:  []string{"host"},
+			Prefix: "server",
+			Usage:  "server host name",
+			Path:   "Server.Host",
+			Field:  p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					*p = s
+					return nil
+				},
+				Quote: true,
+			},
+		})
+	}
+	{
+		p := &t.Server.Port
+		flags = append(flags, &command.StaticFlag{
+			Names:  []string{"p", "port"},
+			Prefix: "server",
+			Usage:  "server port number",
+			Path:   "Server.Port",
+			Field:  p,
+			Init:   "8080",
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					*p = int(sV)
+					return nil
+				},
+			},
+		})
+	}
+	if t.Proxy != nil {
+		{
+			p := &t.Proxy.Host
+			flags = append(flags, &command.StaticFlag{
+				Names:  []string{"host"},
+				Prefix: "proxy",
+				Usage:  "proxy host name",
+				Path:   "Proxy.Host",
+				Field:  p,
+				Value: &command.StaticValue{
+					Get: func() string { return fmt.Sprint(*p) },
+					SetFunc: func(s string) error {
+						*p = s
+						return nil
+					},
+					Quote: true,
+				},
+			})
+		}
+		{
+			p := &t.Proxy.Port
+			flags = append(flags, &command.StaticFlag{
+				Names:  []string{"p", "port"},
+				Prefix: "proxy",
+				Usage:  "proxy port number",
+				Path:   "Proxy.Port",
+				Field:  p,
+				Init:   "8080",
+				Value: &command.StaticValue{
+					Get: func() string { return fmt.Sprint(*p) },
+					SetFunc: func(s string) error {
+						sV, err := strconv.ParseInt(s, 10, 64)
+						if err != nil {
+							return err
+						}
+						*p = int(sV)
+						return nil
+					},
+				},
+			})
+		}
+	}
+	if t.Backup == nil {
+		t.Backup = new(Server)
+	}
+	if t.Backup != nil {
+		{
+			p := &t.Backup.Host
+			flags = append(flags, &command.StaticFlag{
+				Names:     []string{"host"},
+				Prefix:    "backup",
+				Usage:     "backup host name",
+				Path:      "Backup.Host",
+				Field:     p,
+				Allocated: []interface{}{&t.Backup},
+				Value: &command.StaticValue{
+					Get: func() string { return fmt.Sprint(*p) },
+					SetFunc: func(s string) error {
+						*p = s
+						return nil
+					},
+					Quote: true,
+				},
+			})
+		}
+		{
+			p := &t.Backup.Port
+			flags = append(flags, &command.StaticFlag{
+				Names:     []string{"p", "port"},
+				Prefix:    "backup",
+				Usage:     "backup port number",
+				Path:      "Backup.Port",
+				Field:     p,
+				Allocated: []interface{}{&t.Backup},
+				Init:      "8080",
+				Value: &command.StaticValue{
+					Get: func() string { return fmt.Sprint(*p) },
+					SetFunc: func(s string) error {
+						sV, err := strconv.ParseInt(s, 10, 64)
+						if err != nil {
+							return err
+						}
+						*p = int(sV)
+						return nil
+					},
+				},
+			})
+		}
+	}
+	{
+		p := &t.Embedded.Host
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"host"},
+			Usage: "host name",
+			Path:  "Embedded.Host",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					*p = s
+					return nil
+				},
+				Quote: true,
+			},
+		})
+	}
+	{
+		p := &t.Embedded.Port
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"p", "port"},
+			Usage: "port number",
+			Path:  "Embedded.Port",
+			Field: p,
+			Init:  "8080",
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					*p = int(sV)
+					return nil
+				},
+			},
+		})
+	}
+	{
+		p := &t.Limit
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"limit"},
+			Usage: "optional limit",
+			Path:  "Limit",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string {
+					if *p == nil {
+						return ""
+					}
+					return fmt.Sprint(**p)
+				},
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return err
+					}
+					v := int(sV)
+					*p = &v
+					return nil
+				},
+				Optional: true,
+			},
+		})
+	}
+	{
+		p := &t.Label
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"label"},
+			Usage: "optional label",
+			Path:  "Label",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string {
+					if *p == nil {
+						return ""
+					}
+					return fmt.Sprint(**p)
+				},
+				SetFunc: func(s string) error {
+					v := s
+					*p = &v
+					return nil
+				},
+				Optional: true,
+				Quote:    true,
+			},
+		})
+	}
+	{
+		p := &t.Force
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"force"},
+			Usage: "optional force",
+			Path:  "Force",
+			Field: p,
+			Value: &command.StaticValue{
+				Get: func() string {
+					if *p == nil {
+						return ""
+					}
+					return fmt.Sprint(**p)
+				},
+				SetFunc: func(s string) error {
+					sV, err := strconv.ParseBool(s)
+					if err != nil {
+						return err
+					}
+					v := sV
+					*p = &v
+					return nil
+				},
+				Optional: true,
+				Bool:     true,
+			},
+		})
+	}
+	{
+		p := &t.Limits
+		flags = append(flags, &command.StaticFlag{
+			Names: []string{"limits"},
+			Usage: "limits, as key=value",
+			Path:  "Limits",
+			Field: p,
+			Init:  "a=1,b=2",
+			Value: &command.StaticValue{
+				Get: func() string { return fmt.Sprint(*p) },
+				SetFunc: func(s string) error {
+					k, v, err := command.SplitKeyValue(s)
+					if err != nil {
+						return err
+					}
+					vV, err := strconv.ParseUint(v, 10, 64)
+					if err != nil {
+						return err
+					}
+					(*p)[k] = uint(vV)
+					return nil
+				},
+				Reset: func() { *p = make(map[string]uint) },
+			},
+		})
+	}
+	return flags
+}
+
+var _ command.StaticFlags = (*Flags)(nil)
+
+// CommandFlags returns the flags of Funcs, without reflection.
+func (t *Funcs) CommandFlags() []*command.StaticFlag {
+	var flags []*command.StaticFlag
+	return flags
+}
+
+var _ command.StaticFlags = (*Funcs)(nil)
+
+// AddArgs calls Add with the command arguments, without reflection.
+// Its usage is "<int> <sample.Level>".
+func (t *Funcs) AddArgs(args []string) error {
+	if len(args) != 2 {
+		return errors.New("wrong number of arguments")
+	}
+	a0 := args[0]
+	a0V, err := strconv.ParseInt(a0, 10, 64)
+	if err != nil {
+		return err
+	}
+	a1 := args[1]
+	a1V, err := strconv.ParseInt(a1, 10, 64)
+	if err != nil {
+		return err
+	}
+	t.Add(int(a0V), Level(a1V))
+	return nil
+}
+
+// JoinArgs calls Join with the command arguments, without reflection.
+// Its usage is "<string> <[]float64>".
+func (t *Funcs) JoinArgs(args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+	a0 := args[0]
+	rest := make([]float64, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		argV, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return err
+		}
+		rest = append(rest, argV)
+	}
+	return t.Join(a0, rest...)
+}
+
+// EchoArgs calls Echo with the command arguments, without reflection.
+// Its usage is "<[]string>".
+func (t *Funcs) EchoArgs(args []string) error {
+	t.Echo(args)
+	return nil
+}
+
+// HelloArgs calls Hello with the command arguments, without reflection.
+func (t *Funcs) HelloArgs(args []string) error {
+	if len(args) > 0 {
+		return errors.New("function takes no arguments")
+	}
+	t.Hello()
+	return nil
+}
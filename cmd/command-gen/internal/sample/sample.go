@@ -0,0 +1,66 @@
+// Package sample has flags structs for testing command-gen.
+package sample
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//go:generate go run melato.org/command/cmd/command-gen -type Flags,Funcs
+
+type Level int
+
+type Server struct {
+	Host string `usage:"host name"`
+	Port int    `name:"p,port" usage:"port number" init:"8080"`
+}
+
+type Flags struct {
+	Name     string        `name:"n,name" usage:"the name" env:"SAMPLE_NAME"`
+	Verbose  bool          `name:"V" usage:"verbose output"`
+	Debug    int           `name:"d" usage:"debug level" count:"true"`
+	Count    uint8         `usage:"number of times"`
+	Ratio    float32       `usage:"a ratio"`
+	Level    Level         `usage:"the level"`
+	Timeout  time.Duration `usage:"timeout, in nanoseconds"`
+	Tags     []string      `name:"tag" usage:"a tag" default:"none"`
+	Server   Server        `name:"server" usage:"server"`
+	Proxy    *Server       `name:"proxy" usage:"proxy"`
+	Backup   *Server       `name:"backup" usage:"backup" alloc:"optional"`
+	Embedded Server
+	Limit    *int            `usage:"optional limit"`
+	Label    *string         `usage:"optional label"`
+	Force    *bool           `usage:"optional force"`
+	Limits   map[string]uint `usage:"limits, as key=value" init:"a=1,b=2"`
+	Skipped  string          `name:"-"`
+	hidden   int
+}
+
+type Funcs struct {
+	Out strings.Builder `name:"-"`
+}
+
+func (t *Funcs) Add(a int, b Level) {
+	fmt.Fprintln(&t.Out, a+int(b))
+}
+
+func (t *Funcs) Join(sep string, values ...float64) error {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprint(v)
+	}
+	fmt.Fprintln(&t.Out, strings.Join(s, sep))
+	return nil
+}
+
+func (t *Funcs) Echo(args []string) {
+	fmt.Fprintln(&t.Out, strings.Join(args, " "))
+}
+
+func (t *Funcs) Hello() {
+	fmt.Fprintln(&t.Out, "hello")
+}
+
+func (t *Funcs) Ignored(m map[string]int) {
+}
@@ -0,0 +1,110 @@
+package sample
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"melato.org/command"
+)
+
+// reflected has the same fields as Flags, but not the generated methods, so its flags are extracted by reflection.
+type reflected Flags
+
+func flagInfo(t *testing.T, flags interface{}) []*command.FlagInfo {
+	var cmd command.SimpleCommand
+	info, err := cmd.Flags(flags).FlagInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range info {
+		// the fields are different
+		f.Field = nil
+	}
+	return info
+}
+
+func TestStaticFlags(t *testing.T) {
+	flags := &Flags{Proxy: &Server{}}
+	flags.Server.Port = 8080
+	flags.Tags = []string{"a"}
+	static := flagInfo(t, flags)
+	dynamic := flagInfo(t, (*reflected)(flags))
+	if len(static) != len(dynamic) {
+		t.Fatalf("static: %d flags, reflection: %d flags", len(static), len(dynamic))
+	}
+	for i, f := range static {
+		if !reflect.DeepEqual(f, dynamic[i]) {
+			t.Errorf("static: %+v reflection: %+v", f, dynamic[i])
+		}
+	}
+	// FlagInfo does not modify the flags
+	if flags.Limits != nil || !reflect.DeepEqual(flags.Tags, []string{"a"}) {
+		t.Errorf("limits: %v tags: %v", flags.Limits, flags.Tags)
+	}
+}
+
+func TestStaticValues(t *testing.T) {
+	flags := &Flags{Tags: []string{"default"}}
+	values := make(map[string]*command.StaticFlag)
+	for _, f := range flags.CommandFlags() {
+		name := f.Names[0]
+		if f.Prefix != "" {
+			name = f.Prefix + "." + name
+		}
+		values[name] = f
+	}
+	set := [][2]string{{"n", "x"}, {"V", "true"}, {"d", "true"}, {"d", "true"}, {"count", "3"}, {"level", "-2"}, {"tag", "a"}, {"tag", "b"}, {"server.p", "80"}, {"limit", "0"}, {"force", "true"}}
+	for _, s := range set {
+		if err := values[s[0]].Value.Set(s[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := &Flags{Name: "x", Verbose: true, Debug: 2, Count: 3, Level: -2, Tags: []string{"a", "b"}}
+	expected.Server.Port = 80
+	limit, force := 0, true
+	expected.Limit, expected.Force = &limit, &force
+	// allocated by CommandFlags(), and not reset, since there is no parsing
+	expected.Backup = &Server{}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("%+v", flags)
+	}
+	if s := values["n"].Value.String(); s != `"x"` {
+		t.Errorf("String(): %s", s)
+	}
+	if s := values["label"].Value.String(); s != "" {
+		t.Errorf("String(): %s", s)
+	}
+	if err := values["count"].Value.Set("-1"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	var funcs Funcs
+	calls := []struct {
+		fn   func([]string) error
+		args string
+	}{
+		{funcs.AddArgs, "1 2"},
+		{funcs.JoinArgs, ", 1.5 2"},
+		{funcs.JoinArgs, "-"},
+		{funcs.EchoArgs, "a b"},
+		{funcs.HelloArgs, ""},
+	}
+	for _, c := range calls {
+		if err := c.fn(strings.Fields(c.args)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "3\n1.5,2\n\na b\nhello\n"
+	if funcs.Out.String() != expected {
+		t.Errorf("%q", funcs.Out.String())
+	}
+	if err := funcs.AddArgs([]string{"1"}); err == nil {
+		t.Errorf("expected error")
+	}
+	if err := funcs.HelloArgs([]string{"1"}); err == nil {
+		t.Errorf("expected error")
+	}
+}
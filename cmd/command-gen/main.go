@@ -0,0 +1,58 @@
+// command-gen generates flag bindings and argument conversion for flags structs, without reflection.
+// It is meant to be used with go generate, for binaries where the startup time
+// or the binary size cost of reflection matters:
+//
+//	//go:generate go run melato.org/command/cmd/command-gen -type Flags,Funcs
+//
+// For each type, it generates:
+//   - A CommandFlags() method, which implements command.StaticFlags.
+//     SimpleCommand uses it instead of extracting the flags by reflection.
+//     The flags have the same names, prefixes and usage as the flags extracted by reflection.
+//   - A <Method>Args(args []string) error method for each exported method of the type
+//     whose arguments have basic types and that returns nothing or an error.
+//     It converts the arguments and calls the method, and it can be used with RunMethodArgs()
+//     instead of using RunFunc() with the method.
+//
+// The generated code must be generated again whenever the flags structs change.
+//
+// Fields with a "parser" tag, interface fields, and types that need a parser that is registered at runtime
+// are not supported, since they depend on runtime values.  The types that use them should not be generated.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names")
+	output := flag.String("o", "command_gen.go", "output file, relative to the package directory")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: command-gen -type <type>,... [-o <file>] [<package-dir>]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outFile := filepath.Join(dir, *output)
+	g := &generator{warn: os.Stderr}
+	src, err := g.generate(dir, filepath.Base(outFile), strings.Split(*typeNames, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "command-gen:", err)
+		os.Exit(1)
+	}
+	err = os.WriteFile(outFile, src, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "command-gen:", err)
+		os.Exit(1)
+	}
+}
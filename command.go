@@ -4,12 +4,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 
 	"melato.org/command/internal/util"
+	"melato.org/command/reflx"
 )
 
 /** A Command is a struct type, whose fields are used to specify the CLI flags.
@@ -22,8 +25,9 @@ The usage string of the whole command is taken from the "usage" tag of an export
 
 type command interface {
 	/** run the command.
-	 */
-	run(args []string) error
+	pm is used to convert the arguments of a run function.
+	*/
+	run(args []string, pm reflx.ParserManager, inj *injector) error
 
 	/** Called before any other method, as a constructor
 	It may set default values, which are shown in the usage help.
@@ -38,6 +42,30 @@ type command interface {
 
 	cleanup() error
 
+	// Return the middleware that wraps the execution of this command and its descendants.
+	wrappers() []Middleware
+
+	// Return the directory for crash reports, and whether crash reports are enabled.
+	crashReportDir() (string, bool)
+
+	// Return the executable name prefix for external plugin subcommands, and whether plugins are enabled.
+	plugins() (string, bool)
+
+	// Return the name of the dry-run flag, or "" if the command has no dry-run flag.
+	dryRunFlag() string
+
+	// Return true if "@file" arguments should be expanded.
+	responseFiles() bool
+
+	// Convert the command arguments to the values that are passed to the run function, not including any injected values.
+	decodeArgs(args []string, pm reflx.ParserManager) ([]reflect.Value, error)
+
+	// Return the parsers for this command and its descendants, or nil to use the parsers of the parent command.
+	parsers() reflx.ParserManager
+
+	// Return the values that are injected into the run functions of this command and its descendants, by type.
+	providers() map[reflect.Type]provider
+
 	/** Returns usage information
 	 */
 	usage() *Usage
@@ -56,6 +84,8 @@ type commandInfo struct {
 	Flags          []*commandFlag
 	extractedFlags bool
 	FlagSet        *flag.FlagSet
+	dryRun         bool
+	parsers        reflx.ParserManager // parsers for flags and arguments, see inheritParsers
 }
 
 func (t *commandInfo) Init() error {
@@ -63,14 +93,69 @@ func (t *commandInfo) Init() error {
 		return nil
 	}
 	t.extractedFlags = true
-	err := t.Command.init()
+	// The "init" tags are applied before Command.Init(), so that Init() can use or override them.
+	// This needs an extraction before Command.Init(), only to find the tagged fields.
+	flags, err := t.extractFlags()
+	if err != nil {
+		return err
+	}
+	initialized := make(map[interface{}]bool)
+	for _, cf := range flags {
+		initialized[cf.Field] = true
+	}
+	if c, inspecting := t.Command.(*inspectedCommand); inspecting {
+		flags = c.ownFlags(flags)
+	}
+	err = applyInitTags(flags)
+	if err != nil {
+		return err
+	}
+	err = t.Command.init()
 	if err != nil {
 		return err
 	}
 	// extractFlags must be called after Command.Init(),
 	// because Command.Init may create flags by assigning values to struct pointers
-	t.Flags = extractFlags(t.Command.flags(), &flagPrefix{})
-	return nil
+	t.Flags, err = t.extractFlags()
+	if err != nil {
+		return err
+	}
+	// apply the "init" tags of the flags that Command.Init() created
+	var created []*commandFlag
+	for _, cf := range t.Flags {
+		if !initialized[cf.Field] {
+			created = append(created, cf)
+		}
+	}
+	return applyInitTags(created)
+}
+
+func (t *commandInfo) extractFlags() ([]*commandFlag, error) {
+	if static, ok := t.Command.flags().(StaticFlags); ok {
+		return staticFlags(static)
+	}
+	pm := t.parsers
+	if pm == nil {
+		pm = DefaultParsers
+	}
+	return extractFlags(t.Command.flags(), &flagPrefix{}, pm)
+}
+
+// flagUsage returns the usage of a flag, including any prefix usage.
+// Usage.Flags overrides the usage from the struct tags.  It may use any name of the flag, preferably the primary name.
+func (t *commandInfo) flagUsage(cf *commandFlag) string {
+	if len(t.Usage.Flags) > 0 {
+		k := cf.PrimaryNameIndex()
+		if usage, found := t.Usage.Flags[cf.Prefix.ComposeName(cf.Names[k])]; found {
+			return usage
+		}
+		for _, name := range cf.Names {
+			if usage, found := t.Usage.Flags[cf.Prefix.ComposeName(name)]; found {
+				return usage
+			}
+		}
+	}
+	return cf.Prefix.ComposeUsage(cf.Usage)
 }
 
 /** should be called after Init() */
@@ -97,7 +182,7 @@ func (t commandInfoSorter) Less(i, j int) bool {
 }
 
 func createCommandInfo(name string, cmd command) *commandInfo {
-	c := &commandInfo{Name: name, Command: cmd}
+	c := &commandInfo{Name: name, Command: cmd, parsers: cmd.parsers()}
 	usage := cmd.usage()
 	if usage != nil {
 		c.Usage = *usage
@@ -111,14 +196,24 @@ func (c *commandInfo) setFlags(fs *flag.FlagSet) error {
 	if err != nil {
 		return err
 	}
+	err = applyEnv(c.Flags)
+	if err != nil {
+		return err
+	}
 	for _, cf := range c.Flags {
 		k := cf.PrimaryNameIndex()
 		for i, name := range cf.Names {
-			usage := cf.Usage
+			var usage string
 			if i != k {
-				usage = "same as --" + cf.Names[k]
+				usage = fmt.Sprintf(messages.SameAs, cf.Names[k])
 			} else {
-				usage = cf.Prefix.ComposeUsage(usage)
+				usage = c.flagUsage(cf)
+				if isCounter(cf.Value) {
+					usage += " " + messages.Repeated
+				}
+				if cf.Env != "" {
+					usage += " ($" + cf.Env + ")"
+				}
 			}
 			fs.Var(cf.Value, cf.Prefix.ComposeName(name), usage)
 		}
@@ -129,31 +224,36 @@ func (c *commandInfo) setFlags(fs *flag.FlagSet) error {
 func (u *commandInfo) optionsString(i, n int) string {
 	var options string
 	if i == 0 {
-		options = "Global Options"
+		options = messages.GlobalOptions
 	} else if i == n-1 {
-		options = "Options"
+		options = messages.Options
 	} else {
-		options = u.Name + " Options"
+		options = fmt.Sprintf(messages.CommandOptions, u.Name)
 	}
 	return options
 }
 
-func showUsage(levels []*commandInfo, commands map[string]*SimpleCommand) {
+func showUsage(w io.Writer, levels []*commandInfo, commands map[string]*SimpleCommand) {
 	var last *commandInfo
+	var plugins map[string]string
 	if len(levels) > 0 {
 		last = levels[len(levels)-1]
+		if prefix, enabled := pluginPrefix(last.Command, levels); enabled {
+			plugins = findPlugins(prefix)
+		}
 	}
+	hasCommands := len(commands) > 0 || len(plugins) > 0
 	if last != nil {
 		u := last.Usage
 		if u.Short != "" {
-			fmt.Println(u.Short)
+			fmt.Fprintln(w, u.Short)
 		}
 		if u.Long != "" {
-			fmt.Println()
-			fmt.Println(u.Long)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, u.Long)
 		}
-		fmt.Println()
-		fmt.Println("Usage:")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, messages.Usage)
 		var cargs []interface{}
 		for _, ci := range levels {
 			cargs = append(cargs, ci.Name)
@@ -161,7 +261,7 @@ func showUsage(levels []*commandInfo, commands map[string]*SimpleCommand) {
 				cargs = append(cargs, "[options]")
 			}
 		}
-		if len(commands) > 0 {
+		if hasCommands {
 			cargs = append(cargs, "<command>")
 		}
 		argsUsage := u.Use
@@ -169,21 +269,29 @@ func showUsage(levels []*commandInfo, commands map[string]*SimpleCommand) {
 			cargs = append(cargs, argsUsage)
 		}
 
-		fmt.Println(cargs...)
+		fmt.Fprintln(w, cargs...)
+
+		if len(u.Args) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, messages.Arguments)
+			for _, arg := range u.Args {
+				fmt.Fprintf(w, "  %s\n", arg)
+			}
+		}
 
 		if len(u.Examples) > 0 {
-			fmt.Println()
-			fmt.Println("Examples:")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, messages.Examples)
 			for i, ex := range u.Examples {
 				if i > 0 {
-					fmt.Println()
+					fmt.Fprintln(w)
 				}
 				lines := util.SplitLines(ex)
 				for i, line := range lines {
 					if i == 0 {
-						fmt.Printf("  %s %s\n", levels[0].Name, line)
+						fmt.Fprintf(w, "  %s %s\n", levels[0].Name, line)
 					} else {
-						fmt.Printf("  %s\n", line)
+						fmt.Fprintf(w, "  %s\n", line)
 					}
 				}
 			}
@@ -192,54 +300,74 @@ func showUsage(levels []*commandInfo, commands map[string]*SimpleCommand) {
 	for n, i := len(levels), len(levels)-1; i >= 0; i-- {
 		u := levels[i]
 		if u.hasOptions() {
-			fmt.Println()
-			fmt.Println(u.optionsString(i, n) + ":")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, u.optionsString(i, n)+":")
+			u.FlagSet.SetOutput(w)
 			u.FlagSet.PrintDefaults()
 		}
 	}
 
-	if len(commands) > 0 {
-		fmt.Println()
-		fmt.Println("Available Commands:")
+	if hasCommands {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, messages.AvailableCommands)
 		var ar []*commandInfo
 		for name, cmd := range commands {
-			ci := createCommandInfo(name, cmd)
-			ar = append(ar, ci)
+			ar = append(ar, &commandInfo{Name: name, Usage: Usage{Short: cmd.Usage.Short}})
+		}
+		for name := range plugins {
+			if _, found := commands[name]; !found {
+				ar = append(ar, &commandInfo{Name: name, Usage: Usage{Short: messages.Plugin}})
+			}
 		}
 		sort.Sort(commandInfoSorter(ar))
 		var nameLen int
 		for _, ci := range ar {
-			w := len(ci.Name)
-			if w > nameLen {
-				nameLen = w
+			width := len(ci.Name)
+			if width > nameLen {
+				nameLen = width
 			}
 		}
 		for _, ci := range ar {
-			fmt.Printf("  %-*s  %s\n", nameLen, ci.Name, ci.Usage.Short)
+			fmt.Fprintf(w, "  %-*s  %s\n", nameLen, ci.Name, ci.Usage.Short)
 		}
 	}
 }
 
 func runCommand(name string, cmd command, args []string, ancestors []*commandInfo) error {
 	var err error
+	if len(ancestors) == 0 {
+		resetSources()
+	}
+	if len(ancestors) == 0 && cmd.responseFiles() {
+		args, err = expandArgs(args)
+		if err != nil {
+			return err
+		}
+	}
 	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	fs.SetOutput(os.Stdout)
 	ci := createCommandInfo(name, cmd)
+	inheritParsers(ci, ancestors)
 	err = ci.setFlags(fs)
 	if err != nil {
 		return err
 	}
-	if err != nil {
-		return err
-	}
 	ci.FlagSet = fs
 	// add a help flag
 	var help bool
 	if fs.Lookup("h") == nil {
-		fs.BoolVar(&help, "h", false, "help")
+		fs.BoolVar(&help, "h", false, messages.Help)
+	}
+	if name := cmd.dryRunFlag(); name != "" {
+		if fs.Lookup(name) != nil {
+			return fmt.Errorf("%s: dry-run flag %s is also a flag of the command", ci.Name, name)
+		}
+		fs.BoolVar(&ci.dryRun, name, false, messages.DryRun)
 	}
 	// parse and apply the flags
-	err = fs.Parse(args)
+	err = fs.Parse(expandCounters(fs, args))
+	markArguments(fs, ci.Flags)
+	resetAllocated(ci.Flags)
 
 	ancestors = append(ancestors, ci)
 	commands := cmd.Commands()
@@ -250,45 +378,42 @@ func runCommand(name string, cmd command, args []string, ancestors []*commandInf
 	}
 
 	if help {
-		showUsage(ancestors, commands)
+		showUsage(os.Stdout, ancestors, commands)
 		os.Exit(0)
 	}
 
 	args2 := fs.Args()
+	if len(args2) > 0 {
+		if _, found := commands[args2[0]]; !found {
+			if prefix, enabled := pluginPrefix(cmd, ancestors); enabled {
+				if path, found := lookPlugin(prefix, args2[0]); found {
+					return runChain(&pluginCommand{command: cmd, name: args2[0], path: path}, ancestors, args2[1:])
+				}
+			}
+		}
+	}
 	if len(commands) > 0 {
 		if len(args2) > 0 {
 			name2 := args2[0]
 			cmd2, found := commands[name2]
 			if found {
 				return runCommand(name2, cmd2, args2[1:], ancestors)
-			} else {
-				ErrorPrintf("no such command: %s\n", name2)
-				showUsage(ancestors, commands)
-				os.Exit(1)
 			}
+			ErrorPrintf("%s\n", fmt.Sprintf(messages.NoSuchCommand, name2))
+			showUsage(os.Stdout, ancestors, commands)
+			os.Exit(1)
 		} else {
-			showUsage(ancestors, commands)
+			showUsage(os.Stdout, ancestors, commands)
 			os.Exit(0)
 		}
 	} else {
-		// call all command-chain Configured() methods just before Run()
-		if cmd.enabledConfig() {
-			for i, a := range ancestors {
-				err := a.Command.configured()
-				if err != nil {
-					cleanup(ancestors[0 : i+1])
-					return err
-				}
-			}
-		}
-		err := cmd.run(args2)
-		cleanup(ancestors)
+		err := runChain(cmd, ancestors, args2)
 		if err != nil && err.Error() == "" {
 			u := cmd.usage()
 			if u != nil && u.Use != "" {
-				err = errors.New("usage: " + u.Use)
+				err = errors.New(fmt.Sprintf(messages.UsageError, u.Use))
 			} else {
-				err = errors.New("wrong usage")
+				err = errors.New(messages.WrongUsage)
 			}
 		}
 		return err
@@ -303,12 +428,60 @@ var ErrorPrintf func(format string, args ...interface{}) = func(format string, a
 func Main(cmd *SimpleCommand) {
 	name := filepath.Base(os.Args[0])
 	err := runCommand(name, cmd, os.Args[1:], nil)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.Code)
+	}
 	if err != nil {
 		ErrorPrintf("%v\n", err)
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) && panicErr.Report == "" {
+			// there is no crash report with the stack trace, so print it
+			ErrorPrintf("\n%s", panicErr.Stack)
+		}
 		os.Exit(1)
 	}
 }
 
+// runChain runs the command, wrapped by the middleware of the command chain.
+// It calls cleanup for the command chain after the middleware returns, even if the middleware does not call next, or if there is a panic.
+// A panic is converted to a *PanicError.
+func runChain(cmd command, ancestors []*commandInfo, args []string) (err error) {
+	inv := newInvocation(ancestors, args)
+	if p, isPlugin := cmd.(*pluginCommand); isPlugin {
+		inv.Path = append(inv.Path, p.name)
+		inv.Flags = append(inv.Flags, nil)
+	}
+	// the number of commands to clean up, which is less than all of them only if a Configured() method fails
+	closing := len(ancestors)
+	defer recoverPanic(inv, ancestors, &err)
+	defer func() {
+		cleanup(ancestors[0:closing])
+	}()
+	return chainMiddleware(inv, ancestors, func() error {
+		return execute(cmd, ancestors, inv, &closing)
+	})()
+}
+
+// execute calls the Configured() methods of the command chain and then runs the command.
+// If a Configured() method fails, it sets *closing to the number of commands that need cleanup.
+func execute(cmd command, ancestors []*commandInfo, inv *Invocation, closing *int) error {
+	// call all command-chain Configured() methods just before Run()
+	if cmd.enabledConfig() {
+		for i, a := range ancestors {
+			err := a.Command.configured()
+			if err != nil {
+				*closing = i + 1
+				return err
+			}
+		}
+	}
+	if inv.DryRun {
+		return dryRun(cmd, ancestors, inv)
+	}
+	return cmd.run(inv.Args, ancestors[len(ancestors)-1].parsers, newInjector(inv, ancestors))
+}
+
 func cleanup(commands []*commandInfo) {
 	for j := len(commands) - 1; j >= 0; j-- {
 		if err2 := commands[j].Command.cleanup(); err2 != nil {
@@ -0,0 +1,957 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"melato.org/command/reflx"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(inv *Invocation, next func() error) error {
+			calls = append(calls, name+":"+strings.Join(inv.Path, "/"))
+			return next()
+		}
+	}
+	var root SimpleCommand
+	root.Wrap(trace("root"))
+	root.Command("sub").Wrap(trace("sub")).RunFunc(func() {
+		calls = append(calls, "run")
+	})
+	err := runCommand("app", &root, []string{"sub"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "root:app/sub sub:app/sub run"
+	actual := strings.Join(calls, " ")
+	if expected != actual {
+		t.Errorf("expected: %s actual: %s", expected, actual)
+	}
+}
+
+type closeFlags struct {
+	closed bool
+}
+
+func (t *closeFlags) Close() error {
+	t.closed = true
+	return nil
+}
+
+func TestPanicCleanup(t *testing.T) {
+	var root SimpleCommand
+	var flags closeFlags
+	root.Flags(&flags)
+	root.Command("sub").RunFunc(func() {
+		panic("boom")
+	})
+	err := runCommand("app", &root, []string{"sub"}, nil)
+	pe, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+	if pe.Value != "boom" {
+		t.Errorf("panic value: %v", pe.Value)
+	}
+	if !flags.closed {
+		t.Errorf("Close() was not called")
+	}
+}
+
+func TestMiddlewareCleanup(t *testing.T) {
+	var root SimpleCommand
+	var flags closeFlags
+	var ran bool
+	root.Flags(&flags).Wrap(func(inv *Invocation, next func() error) error {
+		return errors.New("denied")
+	}).RunFunc(func() {
+		ran = true
+	})
+	err := runCommand("app", &root, nil, nil)
+	if err == nil || err.Error() != "denied" {
+		t.Errorf("expected denied, got %v", err)
+	}
+	if ran {
+		t.Errorf("the command ran")
+	}
+	if !flags.closed {
+		t.Errorf("Close() was not called")
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	var root SimpleCommand
+	var flags closeFlags
+	root.Flags(&flags).Wrap(func(inv *Invocation, next func() error) error {
+		panic("middleware")
+	}).RunFunc(func() {})
+	err := runCommand("app", &root, nil, nil)
+	pe, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+	if len(pe.Stack) == 0 {
+		t.Errorf("no stack trace")
+	}
+	if !flags.closed {
+		t.Errorf("Close() was not called")
+	}
+}
+
+type dryRunFlags struct {
+	N    int    `name:"n"`
+	Name string `name:"name" env:"COMMAND_TEST_NAME"`
+}
+
+func TestDryRun(t *testing.T) {
+	t.Setenv("COMMAND_TEST_NAME", "env")
+	var root SimpleCommand
+	var flags dryRunFlags
+	var ran bool
+	root.Flags(&flags).DryRunFlag("dry-run").RunFunc(func(a int, b string) {
+		ran = true
+	})
+	// the output goes to the Stdout of the invocation
+	var out strings.Builder
+	root.Wrap(func(inv *Invocation, next func() error) error {
+		inv.Stdout = &out
+		return next()
+	})
+	err := runCommand("app", &root, []string{"-n", "3", "-dry-run", "1", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran {
+		t.Errorf("the command ran")
+	}
+	var actual dryRunOutput
+	err = json.Unmarshal([]byte(out.String()), &actual)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	expected := dryRunOutput{
+		Command: []string{"app"},
+		Flags: []dryRunFlag{
+			{Command: "app", Name: "n", Value: 3.0, Source: "command line"},
+			{Command: "app", Name: "name", Value: "env", Source: "env"},
+		},
+		Args: []interface{}{1.0, "x"},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestDryRunConflict(t *testing.T) {
+	var root SimpleCommand
+	var flags dryRunFlags
+	root.Flags(&flags).DryRunFlag("n").RunFunc(func() {})
+	err := runCommand("app", &root, nil, nil)
+	if err == nil {
+		t.Errorf("expected a flag conflict error")
+	}
+}
+
+type sourceFlags struct {
+	A int `name:"a"`
+	B int `name:"b" env:"COMMAND_TEST_B"`
+	C int `name:"c"`
+}
+
+func TestFlagSource(t *testing.T) {
+	t.Setenv("COMMAND_TEST_B", "2")
+	var root SimpleCommand
+	var flags sourceFlags
+	var sources []FlagSource
+	root.Flags(&flags).RunFunc(func() {
+		sources = []FlagSource{SourceOf(&flags.A), SourceOf(&flags.B), SourceOf(&flags.C)}
+	})
+	err := runCommand("app", &root, []string{"-a", "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FlagSource{SourceArgument, SourceEnv, SourceDefault}
+	for i, source := range expected {
+		if sources[i] != source {
+			t.Errorf("%d: expected %v, got %v", i, source, sources[i])
+		}
+	}
+	if flags.B != 2 {
+		t.Errorf("B=%d", flags.B)
+	}
+}
+
+func TestFlagSourceReset(t *testing.T) {
+	var root SimpleCommand
+	var flags sourceFlags
+	var set bool
+	root.Flags(&flags).RunFunc(func() {
+		set = IsSet(&flags.A)
+	})
+	for _, args := range [][]string{{"-a", "1"}, nil} {
+		err := runCommand("app", &root, args, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set != (len(args) > 0) {
+			t.Errorf("%v: IsSet=%v", args, set)
+		}
+	}
+}
+
+type envSliceFlags struct {
+	Tags   []string          `name:"tag" env:"COMMAND_TEST_TAGS"`
+	Labels map[string]string `name:"label" env:"COMMAND_TEST_LABELS"`
+}
+
+func TestEnvSlice(t *testing.T) {
+	t.Setenv("COMMAND_TEST_TAGS", "e")
+	t.Setenv("COMMAND_TEST_LABELS", "a=1")
+	var root SimpleCommand
+	var flags envSliceFlags
+	root.Flags(&flags).RunFunc(func() {})
+	err := runCommand("app", &root, []string{"-tag", "c", "-label", "b=2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flags.Tags, []string{"c"}) {
+		t.Errorf("Tags=%v", flags.Tags)
+	}
+	if !reflect.DeepEqual(flags.Labels, map[string]string{"b": "2"}) {
+		t.Errorf("Labels=%v", flags.Labels)
+	}
+}
+
+// staticSourceFlags has hand-written bindings, like the ones generated by cmd/command-gen.
+type staticSourceFlags struct {
+	A int
+	B []string
+}
+
+func (t *staticSourceFlags) CommandFlags() []*StaticFlag {
+	return []*StaticFlag{
+		{Names: []string{"a"}, Env: "COMMAND_TEST_A", Path: "A", Field: &t.A, Value: &StaticValue{
+			Get: func() string { return fmt.Sprint(t.A) },
+			SetFunc: func(s string) error {
+				_, err := fmt.Sscan(s, &t.A)
+				return err
+			},
+		}},
+		{Names: []string{"b"}, Prefix: "p", Path: "B", Field: &t.B, Value: &StaticValue{
+			Get: func() string { return fmt.Sprint(t.B) },
+			SetFunc: func(s string) error {
+				t.B = append(t.B, s)
+				return nil
+			},
+			Reset: func() { t.B = t.B[:0] },
+		}},
+	}
+}
+
+func TestStaticFlags(t *testing.T) {
+	t.Setenv("COMMAND_TEST_A", "3")
+	var root SimpleCommand
+	flags := staticSourceFlags{B: []string{"default"}}
+	var source FlagSource
+	root.Flags(&flags).RunFunc(func() {
+		source = SourceOf(&flags.A)
+	})
+	err := runCommand("app", &root, []string{"-p.b", "x", "-p.b", "y"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.A != 3 || source != SourceEnv {
+		t.Errorf("A=%d source=%v", flags.A, source)
+	}
+	if !reflect.DeepEqual(flags.B, []string{"x", "y"}) {
+		t.Errorf("B=%v", flags.B)
+	}
+}
+
+type methodsObj struct {
+	calls []string
+}
+
+func (t *methodsObj) Init() error {
+	return nil
+}
+
+func (t *methodsObj) DryRun(s string) {
+	t.calls = append(t.calls, "dry-run "+s)
+}
+
+func (t *methodsObj) Skip() {
+}
+
+func (t *methodsObj) Value() int {
+	return 0
+}
+
+func (t *methodsObj) Notify(ch chan string) {
+}
+
+func (t *methodsObj) Usage() map[string]Usage {
+	return map[string]Usage{"DryRun": {Short: "dry run"}}
+}
+
+func TestMethods(t *testing.T) {
+	var root SimpleCommand
+	var obj methodsObj
+	root.Methods(&obj, "skip")
+	commands := root.Commands()
+	if len(commands) != 1 {
+		t.Fatalf("commands: %v", commands)
+	}
+	sub := commands["dry-run"]
+	if sub == nil || sub.Usage.Short != "dry run" || sub.Usage.Use != "<string>" {
+		t.Fatalf("dry-run: %v", sub)
+	}
+	err := runCommand("app", &root, []string{"dry-run", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(obj.calls, ",") != "dry-run x" {
+		t.Errorf("calls: %v", obj.calls)
+	}
+}
+
+type structRoot struct {
+	Verbose bool    `name:"v"`
+	Add     *addCmd `cmd:"add" short:"add numbers"`
+	Echo    echoCmd `cmd:""`
+}
+
+type addCmd struct {
+	N int `name:"n"`
+}
+
+func (t *addCmd) Run(a, b int) {
+	t.N += a + b
+}
+
+type echoCmd struct {
+	Prefix string
+	last   string
+}
+
+func (t *echoCmd) Run(s string) {
+	t.last = t.Prefix + s
+}
+
+func TestStructCommand(t *testing.T) {
+	var root structRoot
+	cmd := StructCommand(&root)
+	if cmd.Commands()["add"].Usage.Short != "add numbers" {
+		t.Errorf("missing short usage")
+	}
+	err := runCommand("app", cmd, []string{"-v", "add", "-n", "1", "2", "3"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.Verbose || root.Add.N != 6 {
+		t.Errorf("verbose=%v n=%d", root.Verbose, root.Add.N)
+	}
+	err = runCommand("app", cmd, []string{"echo", "-prefix", "p:", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Echo.last != "p:x" {
+		t.Errorf("echo: %s", root.Echo.last)
+	}
+}
+
+type intRunCmd struct{}
+
+func (t *intRunCmd) Run() int {
+	return 0
+}
+
+func TestStructCommandSignature(t *testing.T) {
+	defer func() {
+		r := recover()
+		if s, _ := r.(string); !strings.Contains(s, "func() int") {
+			t.Errorf("panic: %v", r)
+		}
+	}()
+	StructCommand(&intRunCmd{})
+}
+
+func TestSetMessages(t *testing.T) {
+	defer SetMessages(DefaultMessages())
+	err := SetMessages(Messages{Usage: "100% usage:", SameAs: "wie --%s", NoSuchCommand: "%d %s", UsageError: "usage"})
+	if err == nil {
+		t.Fatalf("expected errors for the invalid formats")
+	}
+	if messages.Usage != "100% usage:" || messages.SameAs != "wie --%s" {
+		t.Errorf("valid messages were not set")
+	}
+	if messages.NoSuchCommand != DefaultMessages().NoSuchCommand || messages.UsageError != DefaultMessages().UsageError {
+		t.Errorf("invalid messages were set")
+	}
+	if err := checkFormat("100%% %s"); err != nil {
+		t.Error(err)
+	}
+	for _, s := range []string{"%s %s", "%", "%v"} {
+		if checkFormat(s) == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestCheckExamples(t *testing.T) {
+	var root SimpleCommand
+	var flags sourceFlags
+	root.Flags(&flags)
+	add := root.Command("add").RunFunc(func(a, b int) {})
+	root.Example("-a 1 add 2 3 > out")
+	root.Example("-a x add 2 3")
+	root.Example("-z add 2 3")
+	root.Example("sub")
+	add.Example("add 1").Example("add 1 y")
+	errs := CheckExamples(&root, "app")
+	if len(errs) != 5 {
+		t.Errorf("expected 5 errors: %v", errs)
+	}
+	if flags.A != 0 {
+		t.Errorf("flags were modified: %d", flags.A)
+	}
+	if !errors.Is(errs[1], ErrUnknownFlag) || !errors.Is(errs[2], ErrUnknownCommand) {
+		t.Errorf("unknown flag and command: %v, %v", errs[1], errs[2])
+	}
+}
+
+type conflictSub struct {
+	X string
+}
+
+type conflictFlags struct {
+	A conflictSub
+	B conflictSub `name:"b"`
+	C conflictSub
+}
+
+func TestValidate(t *testing.T) {
+	var root SimpleCommand
+	root.Flags(&sourceFlags{})
+	root.Command("sub").Flags(&conflictFlags{})
+	err := root.Validate()
+	e, ok := err.(*FlagConflictError)
+	if !ok || e.Name != "x" || e.OtherField != "A.X" || e.Field != "C.X" {
+		t.Errorf("expected conflict between A.X and C.X: %v", err)
+	}
+	root.Command("sub").Flags(&sourceFlags{})
+	err = root.Validate()
+	e, ok = err.(*FlagConflictError)
+	if !ok || e.Name != "a" || e.Command != "sub" || e.OtherCommand != "" {
+		t.Errorf("expected conflict with ancestor: %v", err)
+	}
+}
+
+type point struct {
+	X, Y int
+}
+
+type parserFlags struct {
+	P    point `name:"p"`
+	Mask uint  `name:"mask" parser:"hex"`
+}
+
+func TestParsers(t *testing.T) {
+	pm := reflx.NewParserManager()
+	pm.SetParser(reflect.TypeOf(point{}), func(v reflect.Value, s string) error {
+		var p point
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		v.Set(reflect.ValueOf(p))
+		return err
+	})
+	var root SimpleCommand
+	var flags parserFlags
+	var arg point
+	root.Parsers(pm)
+	root.Command("run").Flags(&flags).RunFunc(func(p point) {
+		arg = p
+	})
+	err := runCommand("app", &root, []string{"run", "-p", "1,2", "-mask", "0xff", "3,4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.P != (point{1, 2}) || flags.Mask != 255 || arg != (point{3, 4}) {
+		t.Errorf("p=%v mask=%d arg=%v", flags.P, flags.Mask, arg)
+	}
+}
+
+func TestMetaCache(t *testing.T) {
+	pm := reflx.NewParserManager()
+	var root SimpleCommand
+	var flags parserFlags
+	root.Parsers(pm).Flags(&flags)
+	info, err := root.FlagInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// point has no parser, so it is a nested struct
+	if len(info) != 3 {
+		t.Fatalf("expected 3 flags: %d", len(info))
+	}
+	// modifying the extracted flags does not modify the cached metadata
+	ci := createCommandInfo("", &root)
+	ci.Init()
+	ci.Flags[0].Names[0] = "modified"
+	ci.Flags[0].Prefix.Name = "modified"
+	info, err = root.FlagInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info[0].Name != "p.x" {
+		t.Errorf("cached metadata was modified: %s", info[0].Name)
+	}
+	// a parser that is added later is used
+	pm.SetParser(reflect.TypeOf(point{}), func(v reflect.Value, s string) error {
+		return nil
+	})
+	info, err = root.FlagInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 2 || info[0].Name != "p" {
+		t.Errorf("flags: %v %v", len(info), info[0].Name)
+	}
+}
+
+// mapParsers is a parser manager whose type is not comparable.
+type mapParsers struct {
+	parsers map[reflect.Type]reflx.ParseFunc
+}
+
+func (t mapParsers) Parser(typ reflect.Type) (reflx.ParseFunc, bool) {
+	if f, found := t.parsers[typ]; found {
+		return f, true
+	}
+	return DefaultParsers.Parser(typ)
+}
+
+func (t mapParsers) SetParser(typ reflect.Type, f reflx.ParseFunc) {
+	t.parsers[typ] = f
+}
+
+func TestUncomparableParsers(t *testing.T) {
+	pm := mapParsers{parsers: make(map[reflect.Type]reflx.ParseFunc)}
+	var root SimpleCommand
+	var flags struct {
+		P point `name:"p"`
+	}
+	root.Parsers(pm).Command("run").Flags(&flags).RunFunc(func() {})
+	info, err := root.Commands()["run"].AncestorFlagInfo(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 2 {
+		t.Errorf("expected 2 flags: %d", len(info))
+	}
+	err = runCommand("app", &root, []string{"run", "-p.x", "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.P.X != 1 {
+		t.Errorf("%+v", flags)
+	}
+}
+
+func TestGenericRun(t *testing.T) {
+	type level int
+	var root SimpleCommand
+	var calls []string
+	add := root.Command("add")
+	Run2(add, func(name string, n level) error {
+		calls = append(calls, fmt.Sprintf("%s=%d", name, n))
+		return nil
+	})
+	RunVariadic(root.Command("sum"), func(values ...float64) error {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		calls = append(calls, fmt.Sprint(sum))
+		return nil
+	})
+	var flags sourceFlags
+	Run0(Flags(root.Command("flags"), &flags), func() error {
+		calls = append(calls, fmt.Sprint(flags.A))
+		return nil
+	})
+	for _, args := range [][]string{{"add", "x", "3"}, {"sum", "1.5", "2"}, {"flags", "-a", "4"}} {
+		if err := runCommand("app", &root, args, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := runCommand("app", &root, []string{"add", "x", "y"}, nil); err == nil {
+		t.Errorf("expected error")
+	}
+	expected := "x=3 3.5 4"
+	if actual := strings.Join(calls, " "); actual != expected {
+		t.Errorf("expected: %s actual: %s", expected, actual)
+	}
+	if use := add.Usage.Use; use != "<string> <command.level>" {
+		t.Errorf("use: %s", use)
+	}
+}
+
+func TestGenericRunAny(t *testing.T) {
+	pm := reflx.NewParserManager()
+	pm.SetParser(reflect.TypeOf(point{}), func(v reflect.Value, s string) error {
+		var p point
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		v.Set(reflect.ValueOf(p))
+		return err
+	})
+	var root SimpleCommand
+	var p point
+	RunAny1(root.Parsers(pm), func(a point) error {
+		p = a
+		return nil
+	})
+	if err := runCommand("app", &root, []string{"1,2"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{1, 2}) {
+		t.Errorf("point: %v", p)
+	}
+	// types without a parser, and injected types, are reported when the command is specified
+	expectPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	expectPanic("chan", func() {
+		RunAny1(new(SimpleCommand), func(c chan int) error { return nil })
+	})
+	expectPanic("context", func() {
+		RunAny1(new(SimpleCommand), func(ctx context.Context) error { return nil })
+	})
+}
+
+type injectService struct {
+	name string
+}
+
+func TestInject(t *testing.T) {
+	var root SimpleCommand
+	var flags sourceFlags
+	var buf strings.Builder
+	Provide(root.Flags(&flags), &injectService{name: "svc"})
+	root.Wrap(func(inv *Invocation, next func() error) error {
+		inv.Stdout = &buf
+		return next()
+	})
+	var result []string
+	sub := root.Command("sub")
+	sub.RunFunc(func(ctx context.Context, w io.Writer, a string, f *sourceFlags, svc *injectService, path CommandPath, b int) {
+		fmt.Fprintf(w, "%s %d", a, b)
+		result = []string{fmt.Sprint(ctx != nil), fmt.Sprint(f.A), svc.name, strings.Join(path, "/")}
+	})
+	if sub.Usage.Use != "<string> <int>" {
+		t.Errorf("use: %s", sub.Usage.Use)
+	}
+	if types, _ := sub.ArgTypes(); len(types) != 2 {
+		t.Errorf("arg types: %v", types)
+	}
+	err := runCommand("app", &root, []string{"-a", "5", "sub", "x", "2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "true 5 svc app/sub"
+	if actual := strings.Join(result, " "); actual != expected {
+		t.Errorf("expected: %s actual: %s", expected, actual)
+	}
+	if buf.String() != "x 2" {
+		t.Errorf("output: %s", buf.String())
+	}
+	// a registered type that is not provided, local to the test, so that the registration does not affect other tests
+	type missingService struct{}
+	Inject[*missingService]()
+	root.Command("missing").RunFunc(func(svc *missingService) {})
+	err = runCommand("app", &root, []string{"missing"}, nil)
+	if err == nil {
+		t.Errorf("expected error")
+	}
+	// an argument type without a parser is not injected
+	root.Command("noparser").RunFunc(func(c chan int) {})
+	err = runCommand("app", &root, []string{"noparser", "x"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "no parser") {
+		t.Errorf("expected a parser error, got %v", err)
+	}
+}
+
+func TestInjectInheritedParsers(t *testing.T) {
+	pm := reflx.NewParserManager()
+	pm.SetParser(reflect.TypeOf(point{}), func(v reflect.Value, s string) error {
+		var p point
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		v.Set(reflect.ValueOf(p))
+		return err
+	})
+	var root SimpleCommand
+	root.Parsers(pm)
+	var p point
+	run := root.Command("run").RunFunc(func(a point) { p = a })
+	if run.Usage.Use == "" {
+		t.Errorf("empty usage")
+	}
+	if types, _ := run.ArgTypes(); len(types) != 1 {
+		t.Errorf("arg types: %v", types)
+	}
+	err := runCommand("app", &root, []string{"run", "1,2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{1, 2}) {
+		t.Errorf("point: %v", p)
+	}
+}
+
+func TestLogFlags(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	logOut, logFlags := log.Writer(), log.Flags()
+	defer func() {
+		log.SetOutput(logOut)
+		log.SetFlags(logFlags)
+	}()
+	var out strings.Builder
+	log.SetOutput(&out)
+	log.SetFlags(log.Lshortfile)
+	file := filepath.Join(t.TempDir(), "log")
+	var root SimpleCommand
+	var flags LogFlags
+	root.Flags(&flags).Command("sub").RunFunc(func(logger *slog.Logger) {
+		logger.Debug("debug")
+		logger.Log(context.Background(), LevelTrace, "trace")
+	})
+	err := runCommand("app", &root, []string{"-log-format", "json", "-log-file", file, "-v", "sub"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	if !strings.Contains(text, `"msg":"debug"`) || strings.Contains(text, "trace") {
+		t.Errorf("log: %s", text)
+	}
+	if flags.file != nil {
+		t.Errorf("log file was not closed")
+	}
+	if slog.Default() != previous {
+		t.Errorf("the default logger was not restored")
+	}
+	// the log package writes to its original output again
+	if log.Flags() != log.Lshortfile {
+		t.Errorf("log flags: %d", log.Flags())
+	}
+	log.Print("after")
+	if !strings.Contains(out.String(), "after") {
+		t.Errorf("log output: %q", out.String())
+	}
+}
+
+type counterFlags struct {
+	Verbose int   `name:"v" usage:"verbosity" count:"true"`
+	Level   uint8 `name:"l" count:"true"`
+	Name    string
+}
+
+func TestCounterFlags(t *testing.T) {
+	var root SimpleCommand
+	var flags counterFlags
+	root.Flags(&flags).RunFunc(func(args []string) {})
+	err := runCommand("app", &root, []string{"-vvv", "-name", "-vv", "-v", "-l", "-l=5", "-ll", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.Verbose != 4 || flags.Level != 7 || flags.Name != "-vv" {
+		t.Errorf("%+v", flags)
+	}
+	if err := CheckExample(&root, "-vv -x"); err == nil {
+		t.Errorf("expected error")
+	}
+	if err := CheckExample(&root, "-vv -v=2 x"); err != nil {
+		t.Error(err)
+	}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	ci := createCommandInfo("app", &root)
+	if err := ci.setFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	if usage := fs.Lookup("v").Usage; usage != "verbosity (may be repeated)" {
+		t.Errorf("usage: %s", usage)
+	}
+}
+
+type optionalFlags struct {
+	Limit *int    `name:"limit"`
+	Label *string `name:"label"`
+	Force *bool   `name:"force"`
+	Sub   *struct {
+		X int
+	}
+	// pointers to types without a parser are not flags
+	Names *[]string
+	Ch    *chan int `name:"ch"`
+}
+
+func TestOptionalFlags(t *testing.T) {
+	var root SimpleCommand
+	var flags optionalFlags
+	root.Flags(&flags)
+	err := runCommand("app", &root, []string{"-limit", "0", "-force"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.Limit == nil || *flags.Limit != 0 || flags.Force == nil || !*flags.Force {
+		t.Errorf("%+v", flags)
+	}
+	if flags.Label != nil || flags.Sub != nil {
+		t.Errorf("%+v", flags)
+	}
+	info, err := root.FlagInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info) != 3 {
+		t.Errorf("expected 3 flags: %d", len(info))
+	}
+}
+
+type allocServer struct {
+	Host string `name:"host"`
+}
+
+type allocFlags struct {
+	A *allocServer `name:"a" alloc:"true"`
+	B *allocServer `name:"b" alloc:"optional"`
+	C *allocServer `name:"c" alloc:"optional"`
+	D *allocServer `name:"d"`
+}
+
+func TestAllocFlags(t *testing.T) {
+	var root SimpleCommand
+	var flags allocFlags
+	root.Flags(&flags)
+	err := runCommand("app", &root, []string{"-c.host", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.A == nil || flags.B != nil || flags.C == nil || flags.C.Host != "x" || flags.D != nil {
+		t.Errorf("%+v", flags)
+	}
+	// a flag that was set in an earlier run does not keep the struct
+	err = runCommand("app", &root, []string{"-b.host", "y"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.B == nil || flags.B.Host != "y" || flags.C != nil {
+		t.Errorf("%+v", flags)
+	}
+	var bad struct {
+		A allocServer `alloc:"true"`
+	}
+	if _, err := new(SimpleCommand).Flags(&bad).FlagInfo(); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+type initServer struct {
+	Port int `name:"port" init:"80"`
+}
+
+// initAllocFlags allocates a nested struct in Init()
+type initAllocFlags struct {
+	Sub *initServer `name:"sub"`
+}
+
+func (t *initAllocFlags) Init() error {
+	t.Sub = &initServer{}
+	return nil
+}
+
+type initFlags struct {
+	Port   int            `name:"port" init:"8080"`
+	Host   string         `name:"host" init:"localhost"`
+	Tags   []string       `name:"tag" init:"a,b"`
+	Limits map[string]int `name:"limit" init:"x=1,y=2"`
+	Level  int            `name:"level" init:"3"`
+	// the "default" tag only describes the default value
+	Dir    string      `name:"dir" default:"current directory"`
+	Count  int         `name:"count" default:"none"`
+	Server *initServer `name:"server"`
+}
+
+func (t *initFlags) Init() error {
+	if t.Level == 3 {
+		t.Level = 4
+	}
+	return nil
+}
+
+func TestInitTags(t *testing.T) {
+	var root SimpleCommand
+	flags := initFlags{Host: "example.com", Server: &initServer{}}
+	root.Flags(&flags)
+	// FlagInfo does not modify the flags, or the structs that they point to
+	if _, err := root.FlagInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if flags.Port != 0 || flags.Tags != nil || flags.Server.Port != 0 {
+		t.Errorf("%+v", flags)
+	}
+	err := runCommand("app", &root, []string{"-tag", "c", "-limit", "z=3"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := initFlags{
+		Port:   8080,
+		Host:   "example.com",
+		Tags:   []string{"c"},
+		Limits: map[string]int{"z": 3},
+		Level:  4,
+		Server: &initServer{Port: 80},
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("%+v", flags)
+	}
+	// the init tags of the structs that Init() allocates are applied after Init()
+	var alloc initAllocFlags
+	err = runCommand("app", new(SimpleCommand).Flags(&alloc), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alloc.Sub == nil || alloc.Sub.Port != 80 {
+		t.Errorf("%+v", alloc.Sub)
+	}
+	var bad struct {
+		Port int `init:"x"`
+	}
+	if _, err := new(SimpleCommand).Flags(&bad).FlagInfo(); err == nil {
+		t.Errorf("expected error")
+	}
+}
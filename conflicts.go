@@ -0,0 +1,110 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"melato.org/command/reflx"
+)
+
+// FlagConflictError is returned when two flags have the same name.
+// The flags may belong to the same command, or to a command and one of its ancestor commands.
+type FlagConflictError struct {
+	// Name is the common flag name.
+	Name string
+
+	// Field and OtherField are the paths of the struct fields of the two flags, e.g. "Sub.X".
+	Field, OtherField string
+
+	// Command and OtherCommand are the command paths of the two flags, if known.
+	// OtherCommand is an ancestor of Command, or the same command.
+	Command, OtherCommand string
+}
+
+func (t *FlagConflictError) Error() string {
+	if t.Command == t.OtherCommand {
+		msg := fmt.Sprintf("flag %s is defined by fields %s and %s", t.Name, t.OtherField, t.Field)
+		if t.Command != "" {
+			msg = t.Command + ": " + msg
+		}
+		return msg
+	}
+	return fmt.Sprintf("flag %s of %s (field %s) is also a flag of %s (field %s)", t.Name, describeCommand(t.Command), t.Field, describeCommand(t.OtherCommand), t.OtherField)
+}
+
+func describeCommand(path string) string {
+	if path == "" {
+		return "the root command"
+	}
+	return "command " + path
+}
+
+// flagNames maps the flag names of a command to their flags.
+func flagNames(flags []*commandFlag) map[string]*commandFlag {
+	names := make(map[string]*commandFlag)
+	for _, cf := range flags {
+		for _, name := range cf.Names {
+			names[cf.Prefix.ComposeName(name)] = cf
+		}
+	}
+	return names
+}
+
+// checkFlagNames checks that the names of the flags of a command are unique.
+func checkFlagNames(flags []*commandFlag) error {
+	names := make(map[string]*commandFlag)
+	for _, cf := range flags {
+		for _, name := range cf.Names {
+			name = cf.Prefix.ComposeName(name)
+			if other, exists := names[name]; exists && other != cf {
+				return &FlagConflictError{Name: name, Field: cf.Path, OtherField: other.Path}
+			}
+			names[name] = cf
+		}
+	}
+	return nil
+}
+
+// Validate checks the command tree for errors that would otherwise be found only when running a command,
+// or not at all.  It calls the Init() method of every flags object in the tree.
+// It returns a *FlagConflictError if two flags of a command have the same name,
+// or if a flag has the same name as a flag of an ancestor command.
+// It is meant to be called from a test, or at program startup.
+func (t *SimpleCommand) Validate() error {
+	return validate(t, nil, nil, DefaultParsers)
+}
+
+func validate(cmd *SimpleCommand, path []string, ancestors []map[string]*commandFlag, pm reflx.ParserManager) error {
+	ci := createCommandInfo("", inspect(cmd))
+	if ci.parsers == nil {
+		ci.parsers = pm
+	}
+	err := ci.Init()
+	if err != nil {
+		if e, ok := err.(*FlagConflictError); ok {
+			e.Command = strings.Join(path, " ")
+			e.OtherCommand = e.Command
+		}
+		return err
+	}
+	for _, cf := range ci.Flags {
+		for _, name := range cf.Names {
+			name = cf.Prefix.ComposeName(name)
+			for i, a := range ancestors {
+				if other, exists := a[name]; exists {
+					return &FlagConflictError{Name: name,
+						Field: cf.Path, OtherField: other.Path,
+						Command: strings.Join(path, " "), OtherCommand: strings.Join(path[:i], " ")}
+				}
+			}
+		}
+	}
+	ancestors = append(ancestors, flagNames(ci.Flags))
+	for _, name := range sortedNames(cmd.Commands()) {
+		err := validate(cmd.Commands()[name], append(path[:len(path):len(path)], name), ancestors, ci.parsers)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
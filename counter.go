@@ -0,0 +1,137 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// counterValue is the flag.Value of an integer field with a count:"true" tag.
+// Each occurrence of the flag increments the field, e.g. -v -v, or -vv.
+// The flag may also be given a value, e.g. -v=3, or -v=false to reset it.
+type counterValue struct {
+	Value reflect.Value
+}
+
+func (t *counterValue) IsBoolFlag() bool {
+	return true
+}
+
+func (t *counterValue) isCounter() bool {
+	return true
+}
+
+func (t *counterValue) String() string {
+	if !t.Value.IsValid() {
+		// the zero value, used by the flag package
+		return "0"
+	}
+	return fmt.Sprint(t.Value)
+}
+
+func (t *counterValue) Set(s string) error {
+	switch t.Value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := ParseCount(s, int64(t.Value.Uint()))
+		if err != nil {
+			return err
+		}
+		t.Value.SetUint(uint64(n))
+	default:
+		n, err := ParseCount(s, t.Value.Int())
+		if err != nil {
+			return err
+		}
+		t.Value.SetInt(n)
+	}
+	return nil
+}
+
+func (t *counterValue) reflectValue() reflect.Value {
+	return t.Value
+}
+
+// ParseCount returns the new value of a counter flag, whose current value is count.
+// "true", which the flag package uses for a flag without a value, increments the count.
+// "false" resets it to 0.  Any other string must be an integer, which replaces the count.
+func ParseCount(s string, count int64) (int64, error) {
+	switch s {
+	case "true":
+		return count + 1, nil
+	case "false":
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func isCountKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isCounter checks if a flag value is a counter.
+func isCounter(value flag.Value) bool {
+	c, ok := value.(interface{ isCounter() bool })
+	return ok && c.isCounter()
+}
+
+func isBoolValue(value flag.Value) bool {
+	b, ok := value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// expandCounters replaces repeated single-letter counter flags, such as -vvv, with separate flags: -v -v -v.
+// It stops at the first argument that is not a flag, like flag.FlagSet.Parse.
+func expandCounters(fs *flag.FlagSet, args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(result, args[i:]...)
+		}
+		name := strings.TrimPrefix(arg[1:], "-")
+		if strings.Contains(name, "=") {
+			result = append(result, arg)
+			continue
+		}
+		if f := fs.Lookup(name); f != nil {
+			result = append(result, arg)
+			if !isBoolValue(f.Value) && i+1 < len(args) {
+				// the flag value
+				i++
+				result = append(result, args[i])
+			}
+			continue
+		}
+		if n, letter := repeatedLetter(name); n > 1 {
+			if f := fs.Lookup(letter); f != nil && isCounter(f.Value) {
+				for j := 0; j < n; j++ {
+					result = append(result, "-"+letter)
+				}
+				continue
+			}
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// repeatedLetter returns the number of repetitions of the letter of a string that consists of a single repeated letter.
+func repeatedLetter(s string) (int, string) {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0, ""
+	}
+	for _, c := range runes[1:] {
+		if c != runes[0] {
+			return 0, ""
+		}
+	}
+	return len(runes), string(runes[0])
+}
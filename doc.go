@@ -5,18 +5,41 @@ from the public (exported) fields of any Go struct type.
 
 It also uses reflection to map other command line arguments (after flags)
 to the arguments of the command's Run method.
+The generic functions Run1, Run2, RunVariadic, etc. check the argument types at compile time.
+Parameters that are not command arguments, such as a context.Context, the flags of the command,
+or application services, are injected if their types are registered.  See Inject and Provide.
+LogFlags are standard logging flags for a root command, which configure a slog.Logger.
 
-It supports scalar fields (string, int, ...).
+It supports scalar fields (string, int, ...), slices, which are set by repeating a flag,
+and maps, which are set by key=value flags.
 
 It also supports struct or struct pointer fields, whose fields are also added as flags.
+A pointer to a scalar, such as *int, is an optional flag, which is nil unless the flag is set.
+A nil struct pointer has no flags, unless it has an alloc:"true" tag, which allocates it,
+or an alloc:"optional" tag, which also resets it to nil after parsing, if none of its flags were set.
 
 The optional "name" and "usage" field tags are used to set the flag name and usage,
 or to exclude a field from being used as a flag.
 See demo.App for an example.
 
+The optional "env" tag specifies an environment variable that sets the flag, unless the flag is also set in the command line.
+SourceOf() and IsSet() report where the value of a flag came from.
+The "count" tag makes an integer field a counter flag, which is incremented each time it appears, e.g. -v -v, or -vv.
+The optional "parser" tag selects a named parser, such as "hex", instead of the parser for the type of the field.
+Parsers for application types can be added to DefaultParsers, or specified for a command tree with SimpleCommand.Parsers().
+
 A command has a hierarchy of sub-commands.  Each sub-command can have additional flags.
+The hierarchy can be built with SimpleCommand.Command(), or declared with struct fields that have a "cmd" tag.
+See StructCommand.
+
+Binaries that cannot afford the startup time of reflection can generate static flag bindings with cmd/command-gen.
+Flags structs that implement StaticFlags are used without reflection.
 
-Flag default values cab be specified in an optional Init() method.
+Flag initial values can be specified with the "init" tag, e.g. init:"8080", or init:"a,b" for a slice,
+or init:"a=1,b=2" for a map.  The tag sets fields that are zero, before the optional Init() method,
+which can also set default values.  The fields of structs that Init() allocates are set after Init().
+The "default" tag only describes the default value in the usage, e.g. default:"current directory",
+and does not set the field.
 
 Flag validation can be performed in an optional Configured() method.
 
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// dryRunFlag describes a flag in the output of a dry run.
+type dryRunFlag struct {
+	Command string      `json:"command"`
+	Name    string      `json:"name"`
+	Value   interface{} `json:"value"`
+	Source  string      `json:"source"`
+}
+
+// dryRunOutput is the output of a dry run.
+type dryRunOutput struct {
+	Command []string      `json:"command"`
+	Flags   []dryRunFlag  `json:"flags"`
+	Args    []interface{} `json:"args"`
+}
+
+// reflectValuer is implemented by flag values that are backed by a struct field.
+type reflectValuer interface {
+	reflectValue() reflect.Value
+}
+
+func (t *fieldValue) reflectValue() reflect.Value {
+	return t.Value
+}
+
+func (t *sliceValue) reflectValue() reflect.Value {
+	return t.Value
+}
+
+func (t *optionalValue) reflectValue() reflect.Value {
+	return t.Value
+}
+
+func (t *mapValue) reflectValue() reflect.Value {
+	return t.Value
+}
+
+// jsonValue converts a value to a form that can be marshaled to JSON.
+func jsonValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem())
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Interface())
+	case reflect.Map:
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[fmt.Sprint(iter.Key().Interface())] = jsonValue(iter.Value())
+		}
+		return values
+	case reflect.Slice:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = jsonValue(v.Index(i))
+		}
+		return values
+	}
+	return v.Interface()
+}
+
+// flagJsonValue returns the effective value of a flag.
+func flagJsonValue(cf *commandFlag) interface{} {
+	if rv, ok := cf.Value.(reflectValuer); ok {
+		return jsonValue(rv.reflectValue())
+	}
+	if cf.Field != nil {
+		return jsonValue(reflect.ValueOf(cf.Field).Elem())
+	}
+	return cf.Value.String()
+}
+
+// dryRun prints the resolved command, its flags and its arguments as JSON, without running the command.
+func dryRun(cmd command, ancestors []*commandInfo, inv *Invocation) error {
+	out := dryRunOutput{Command: inv.Path, Flags: []dryRunFlag{}}
+	for _, ci := range ancestors {
+		for _, cf := range ci.Flags {
+			source := SourceOf(cf.Field).String()
+			name := cf.Prefix.ComposeName(cf.Names[cf.PrimaryNameIndex()])
+			out.Flags = append(out.Flags, dryRunFlag{Command: ci.Name, Name: name, Value: flagJsonValue(cf), Source: source})
+		}
+	}
+	args, err := cmd.decodeArgs(inv.Args, ancestors[len(ancestors)-1].parsers)
+	if err != nil {
+		return err
+	}
+	out.Args = make([]interface{}, len(args))
+	for i, arg := range args {
+		out.Args[i] = jsonValue(arg)
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(inv.Stdout, string(data))
+	return nil
+}
@@ -0,0 +1,200 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ExampleError is an error in a usage example.
+type ExampleError struct {
+	// Path contains the names of the commands from the root command to the command that has the example.
+	Path []string
+
+	// Example is the example, as it appears in the usage.
+	Example string
+
+	// Err is the error found when parsing the example.
+	Err error
+}
+
+func (t *ExampleError) Error() string {
+	return fmt.Sprintf("%s: example %q: %v", strings.Join(t.Path, " "), t.Example, t.Err)
+}
+
+func (t *ExampleError) Unwrap() error {
+	return t.Err
+}
+
+// exampleValue is a flag.Value that checks flag values, without modifying the flags.
+type exampleValue struct {
+	flag.Value
+	check func(s string) error
+}
+
+func (t *exampleValue) Set(s string) error {
+	return t.check(s)
+}
+
+func (t *exampleValue) IsBoolFlag() bool {
+	return isBoolValue(t.Value)
+}
+
+func (t *exampleValue) isCounter() bool {
+	return isCounter(t.Value)
+}
+
+func newExampleValue(value flag.Value) flag.Value {
+	v := &exampleValue{Value: value}
+	switch fv := value.(type) {
+	case *fieldValue:
+		v.check = func(s string) error {
+			return fv.Parse(reflect.New(fv.Value.Type()).Elem(), s)
+		}
+	case *sliceValue:
+		v.check = func(s string) error {
+			return fv.Parse(reflect.New(fv.eval.Type()).Elem(), s)
+		}
+	case *mapValue:
+		v.check = func(s string) error {
+			check := &mapValue{Value: reflect.New(fv.Value.Type()).Elem(), ParseKey: fv.ParseKey, Parse: fv.Parse}
+			return check.Set(s)
+		}
+	case *optionalValue:
+		v.check = func(s string) error {
+			return fv.Parse(reflect.New(fv.pType).Elem(), s)
+		}
+	case *counterValue:
+		v.check = func(s string) error {
+			_, err := ParseCount(s, 0)
+			return err
+		}
+	default:
+		v.check = func(s string) error {
+			return nil
+		}
+	}
+	return v
+}
+
+// ErrUnknownCommand and ErrUnknownFlag are matched, with errors.Is, by the errors of CheckExample
+// for an example that refers to a nonexistent subcommand or flag.
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrUnknownFlag    = errors.New("unknown flag")
+)
+
+// exampleKindError is an error of an example that matches ErrUnknownCommand or ErrUnknownFlag.
+type exampleKindError struct {
+	msg  string
+	kind error
+}
+
+func (t *exampleKindError) Error() string {
+	return t.msg
+}
+
+func (t *exampleKindError) Unwrap() error {
+	return t.kind
+}
+
+// shell operators that end the command line of an example
+var exampleEnd = map[string]bool{"|": true, ">": true, ">>": true, "<": true, "2>": true, "&&": true, "||": true, ";": true}
+
+// CheckExample parses an example command line against a command tree, without running anything.
+// It resolves subcommands, parses the flags, and converts the arguments of the command to the types of its run function.
+// The example does not include the name of the root command, as in Usage.Examples.
+// Any shell redirection or pipe at the end of the example is ignored.
+// It calls the Init() method of a copy of the flags of each command in the example, so that it does not modify the flags.
+func CheckExample(cmd *SimpleCommand, example string) error {
+	args, err := splitArgs(example)
+	if err != nil {
+		return err
+	}
+	for i, arg := range args {
+		if exampleEnd[arg] {
+			args = args[:i]
+			break
+		}
+	}
+	return checkArgs("", cmd, args, nil)
+}
+
+// CheckExamples parses every usage example in a command tree, with CheckExample(), and returns any errors as *ExampleError.
+// name is the name of the root command.
+func CheckExamples(cmd *SimpleCommand, name string) []error {
+	var errs []error
+	checkExamples(cmd, cmd, []string{name}, &errs)
+	return errs
+}
+
+func checkExamples(root, cmd *SimpleCommand, path []string, errs *[]error) {
+	for _, example := range cmd.Usage.Examples {
+		if err := CheckExample(root, example); err != nil {
+			*errs = append(*errs, &ExampleError{Path: path, Example: example, Err: err})
+		}
+	}
+	for _, name := range sortedNames(cmd.Commands()) {
+		subPath := append(append([]string(nil), path...), name)
+		checkExamples(root, cmd.Commands()[name], subPath, errs)
+	}
+}
+
+// checkArgs is like runCommand, except that it does not run anything.
+// It calls the Init() method of a copy of the flags, as FlagInfo() does, so that it does not modify the flags.
+func checkArgs(name string, cmd command, args []string, ancestors []*commandInfo) error {
+	ci := createCommandInfo(name, inspect(cmd))
+	inheritParsers(ci, ancestors)
+	err := ci.Init()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, cf := range ci.Flags {
+		value := newExampleValue(cf.Value)
+		for _, name := range cf.Names {
+			fs.Var(value, cf.Prefix.ComposeName(name), "")
+		}
+	}
+	var help bool
+	if fs.Lookup("h") == nil {
+		fs.BoolVar(&help, "h", false, "")
+	}
+	if name := cmd.dryRunFlag(); name != "" {
+		fs.Bool(name, false, "")
+	}
+	err = fs.Parse(expandCounters(fs, args))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "flag provided but not defined") {
+			return &exampleKindError{msg: err.Error(), kind: ErrUnknownFlag}
+		}
+		return err
+	}
+	if help {
+		return nil
+	}
+	ancestors = append(ancestors, ci)
+	args = fs.Args()
+	commands := cmd.Commands()
+	_, plugins := cmd.plugins()
+	if len(commands) > 0 || plugins {
+		if len(args) == 0 {
+			return nil
+		}
+		sub, found := commands[args[0]]
+		if found {
+			return checkArgs(args[0], sub, args[1:], ancestors)
+		}
+		if plugins {
+			// it may be an external plugin, which cannot be checked
+			return nil
+		}
+		return &exampleKindError{msg: fmt.Sprintf(messages.NoSuchCommand, args[0]), kind: ErrUnknownCommand}
+	}
+	_, err = cmd.decodeArgs(args, ci.parsers)
+	return err
+}
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"os"
+	"reflect"
+)
+
+// InputFile is a run function argument type for a file that is opened for reading.
+// The argument "-" specifies the standard input.
+//
+// The file is opened after all the arguments have been converted, just before calling the run function,
+// and it is closed after the run function returns, before the Close() methods of the command flags are called.
+type InputFile struct {
+	// Path is the command-line argument that specifies the file.
+	Path string
+	*os.File
+}
+
+// Close closes the file, unless it is the standard input.
+func (t *InputFile) Close() error {
+	if t.File == nil || t.File == os.Stdin {
+		return nil
+	}
+	return t.File.Close()
+}
+
+func (t *InputFile) open() error {
+	if t.Path == "-" {
+		t.File = os.Stdin
+		return nil
+	}
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return err
+	}
+	t.File = f
+	return nil
+}
+
+// OutputFile is a run function argument type for a file that is created for writing.
+// The argument "-" specifies the standard output.
+//
+// The file is created after all the arguments have been converted, just before calling the run function,
+// and it is closed after the run function returns, before the Close() methods of the command flags are called.
+// An error from closing the file is returned as the error of the command, if the run function did not return an error.
+type OutputFile struct {
+	// Path is the command-line argument that specifies the file.
+	Path string
+	*os.File
+}
+
+// Close closes the file, unless it is the standard output.
+func (t *OutputFile) Close() error {
+	if t.File == nil || t.File == os.Stdout {
+		return nil
+	}
+	return t.File.Close()
+}
+
+func (t *OutputFile) open() error {
+	if t.Path == "-" {
+		t.File = os.Stdout
+		return nil
+	}
+	f, err := os.Create(t.Path)
+	if err != nil {
+		return err
+	}
+	t.File = f
+	return nil
+}
+
+type argFile interface {
+	open() error
+	Close() error
+}
+
+var inputFileType = reflect.TypeOf((*InputFile)(nil))
+var outputFileType = reflect.TypeOf((*OutputFile)(nil))
+
+func parseInputFile(value reflect.Value, s string) error {
+	value.Set(reflect.ValueOf(&InputFile{Path: s}))
+	return nil
+}
+
+func parseOutputFile(value reflect.Value, s string) error {
+	value.Set(reflect.ValueOf(&OutputFile{Path: s}))
+	return nil
+}
+
+// argTypeName returns the name used for an argument type in the command usage.
+func argTypeName(t reflect.Type) string {
+	switch t {
+	case inputFileType:
+		return "input-file"
+	case outputFileType:
+		return "output-file"
+	}
+	return t.String()
+}
+
+// openFiles opens the file arguments.  If one of them cannot be opened, it closes the ones already opened.
+// The input files are opened before the output files are created,
+// so that an output file is not truncated if an input file cannot be opened.
+func openFiles(in []reflect.Value) error {
+	for _, output := range []bool{false, true} {
+		for _, v := range in {
+			f, ok := v.Interface().(argFile)
+			if !ok {
+				continue
+			}
+			if _, isOutput := f.(*OutputFile); isOutput != output {
+				continue
+			}
+			if err := f.open(); err != nil {
+				closeFiles(in)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closeFiles closes the file arguments and returns the first error.
+// File arguments that have not been opened are skipped.
+func closeFiles(in []reflect.Value) error {
+	var err error
+	for _, v := range in {
+		if f, ok := v.Interface().(argFile); ok {
+			if err2 := f.Close(); err2 != nil && err == nil {
+				err = err2
+			}
+		}
+	}
+	return err
+}
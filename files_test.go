@@ -0,0 +1,109 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileArgs(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	out := filepath.Join(dir, "out.txt")
+	err := os.WriteFile(in, []byte("hello"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root SimpleCommand
+	var inFile *InputFile
+	var outFile *OutputFile
+	root.RunFunc(func(r *InputFile, w *OutputFile) error {
+		inFile, outFile = r, w
+		_, err := io.Copy(w, r)
+		return err
+	})
+	err = runCommand("app", &root, []string{in, out}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("output: %s", data)
+	}
+	// the files are closed after the run function returns
+	if err := inFile.File.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("input file was not closed: %v", err)
+	}
+	if err := outFile.File.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("output file was not closed: %v", err)
+	}
+}
+
+func TestFileArgsStdio(t *testing.T) {
+	var root SimpleCommand
+	var inFile *InputFile
+	var outFile *OutputFile
+	root.RunFunc(func(r *InputFile, w *OutputFile) {
+		inFile, outFile = r, w
+	})
+	err := runCommand("app", &root, []string{"-", "-"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inFile.File != os.Stdin || outFile.File != os.Stdout {
+		t.Errorf("expected stdin and stdout")
+	}
+	// Close does not close the standard input and output
+	if err := inFile.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := outFile.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileArgsMissing(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	var root SimpleCommand
+	var ran bool
+	root.RunFunc(func(r *InputFile, w *OutputFile) {
+		ran = true
+	})
+	err := runCommand("app", &root, []string{filepath.Join(dir, "missing.txt"), out}, nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+	if ran {
+		t.Errorf("the command ran")
+	}
+}
+
+func TestFileArgsOutputFirst(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	err := os.WriteFile(out, []byte("keep"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root SimpleCommand
+	root.RunFunc(func(w *OutputFile, r *InputFile) {})
+	err = runCommand("app", &root, []string{out, filepath.Join(dir, "missing.txt")}, nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+	// the output file is not created before the input file is opened
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "keep" {
+		t.Errorf("output file was truncated: %q", data)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	"melato.org/command/reflx"
 )
@@ -14,6 +15,18 @@ type commandFlag struct {
 	Usage  string
 	Prefix *flagPrefix
 	Value  flag.Value
+	Type   reflect.Type
+	Env    string      // environment variable that sets the flag, from the "env" tag
+	Field  interface{} // pointer to the struct field, used as a key for the flag source
+	Path   string      // the path of the struct field, e.g. "Sub.X", used in errors
+	// InitValue is the value of the "init" tag, which initializes the field before Init(), if the field is zero,
+	// or after Init(), if Init() created the field.
+	InitValue string
+	// Allocated contains pointers to the struct pointer fields with an alloc:"optional" tag that enclose this flag.
+	// Each struct pointer is reset to nil after parsing, if none of its flags were set.
+	Allocated []interface{}
+	// set is true if the flag was set in this run, from the command line or the environment.
+	set bool
 }
 
 func (t *commandFlag) PrimaryNameIndex() int {
@@ -63,31 +76,124 @@ func (t *flagPrefix) Append(p *flagPrefix) *flagPrefix {
 		Usage: t.ComposeUsage(p.Usage)}
 }
 
-func extractFlags(cmdFlags interface{}, prefix *flagPrefix) []*commandFlag {
+// extractFlags extracts the flags of a flags struct pointer, using pm to parse flag values.
+// It returns a *FlagConflictError if two fields have the same flag name.
+func extractFlags(cmdFlags interface{}, prefix *flagPrefix, pm reflx.ParserManager) ([]*commandFlag, error) {
+	flags, err := extractPtrFlags(cmdFlags, prefix, "", pm)
+	if err != nil {
+		return nil, err
+	}
+	return flags, checkFlagNames(flags)
+}
+
+func extractPtrFlags(cmdFlags interface{}, prefix *flagPrefix, path string, pm reflx.ParserManager) ([]*commandFlag, error) {
 	if cmdFlags == nil {
-		return nil
+		return nil, nil
 	}
 	var cmdType reflect.Type = reflect.TypeOf(cmdFlags)
 	//fmt.Println("extractFlags cmdType", cmdType)
 	var t reflect.Type = cmdType.Elem()
 	var value reflect.Value = reflect.ValueOf(cmdFlags).Elem()
-	return extractFlagsV(value, t, prefix)
+	return extractFlagsV(value, t, prefix, path, pm)
 }
 
-func extractFlagsV(value reflect.Value, t reflect.Type, prefix *flagPrefix) []*commandFlag {
-	var flags []*commandFlag
+// fieldKind specifies how a struct field is used for flags
+type fieldKind int
+
+const (
+	scalarField    fieldKind = iota // a single flag
+	sliceField                      // a flag that can be repeated
+	structField                     // a nested struct, whose fields are flags
+	pointerField                    // a pointer to a nested struct, whose fields are flags if it is not nil
+	interfaceField                  // an interface that holds a pointer to a nested struct
+	counterField                    // an integer flag that is incremented each time it appears
+	optionalField                   // a pointer to a scalar, which is nil unless the flag is set
+	mapField                        // a map, whose entries are set by key=value flags
+)
+
+// fieldMeta contains the flag metadata of a struct field, which depends only on the struct type and the parsers.
+type fieldMeta struct {
+	index      int
+	name       string // the field name
+	kind       fieldKind
+	names      []string
+	prefix     *flagPrefix // the prefix for the flags of a nested struct
+	usage      string
+	env        string
+	defaultUse string
+	initValue  string // the "init" tag
+	alloc      string // the "alloc" tag of a struct pointer
+	fType      reflect.Type
+	pType      reflect.Type // the type that is parsed: the field type, or the element type of a slice
+	parse      reflx.ParseFunc
+	parseKey   reflx.ParseFunc // the parser of the keys of a map
+}
+
+// copyPrefix returns a copy of the prefix, so that the flags do not share the cached metadata.
+func (t *fieldMeta) copyPrefix() *flagPrefix {
+	p := *t.prefix
+	return &p
+}
+
+type metaKey struct {
+	t       reflect.Type
+	pm      reflx.ParserManager
+	version int
+}
+
+// versioned is implemented by parser managers that can tell when their parsers change, such as reflx.Parsers.
+type versioned interface {
+	Version() int
+}
+
+var metaLock sync.Mutex
+
+// metaCache caches the flag metadata of struct types, so that extracting flags does not repeat the reflection on struct tags.
+// The metadata is shared by all the flags extracted from a type, so it must not be modified.
+// The version of the parser manager is part of the key, so that parsers that are set later are used.
+var metaCache = make(map[metaKey][]*fieldMeta)
+
+// typeFlags returns the flag metadata of the fields of a struct type.
+// The metadata is not cached for a parser manager whose type is not comparable, since it cannot be part of a map key.
+func typeFlags(t reflect.Type, pm reflx.ParserManager) ([]*fieldMeta, error) {
+	if pm != nil && !reflect.TypeOf(pm).Comparable() {
+		return createTypeFlags(t, pm)
+	}
+	key := metaKey{t: t, pm: pm}
+	if v, ok := pm.(versioned); ok {
+		key.version = v.Version()
+	}
+	metaLock.Lock()
+	fields, found := metaCache[key]
+	metaLock.Unlock()
+	if found {
+		return fields, nil
+	}
+	fields, err := createTypeFlags(t, pm)
+	if err != nil {
+		return nil, err
+	}
+	metaLock.Lock()
+	metaCache[key] = fields
+	metaLock.Unlock()
+	return fields, nil
+}
+
+func createTypeFlags(t reflect.Type, pm reflx.ParserManager) ([]*fieldMeta, error) {
+	var fields []*fieldMeta
 	n := t.NumField()
 	for i := 0; i < n; i++ {
 		var field reflect.StructField = t.Field(i)
 		if !isExported(field.Name) {
 			continue
 		}
-		pm := reflx.NewParserManager()
+		if _, isCommand := field.Tag.Lookup("cmd"); isCommand {
+			// a subcommand, see StructCommand
+			continue
+		}
 		pType := field.Type
-		fValue := value.Field(i)
-		//kind := field.Type.Kind()
-		kind := fValue.Type().Kind()
-		if kind == reflect.Slice {
+		kind := field.Type.Kind()
+		if kind == reflect.Slice || kind == reflect.Map {
 			pType = field.Type.Elem()
 		}
 
@@ -108,56 +214,179 @@ func extractFlagsV(value reflect.Value, t reflect.Type, prefix *flagPrefix) []*c
 		} else {
 			fPrefix.Name = names[0]
 		}
+		meta := &fieldMeta{index: i, name: field.Name, names: names, prefix: fPrefix, fType: field.Type, pType: pType}
 
-		//fmt.Println(i, field.Name, pType, kind, fValue)
+		// a struct, pointer or interface field is a single flag if it has its own parser
+		parserName := field.Tag.Get("parser")
+		_, hasParser := pm.Parser(field.Type)
+		nested := parserName == "" && !hasParser
 
-		if kind == reflect.Struct {
-			//fmt.Println("struct: "+field.Name, fValue)
-			sFlags := extractFlagsV(fValue, fValue.Type(), prefix.Append(fPrefix))
-			flags = append(flags, sFlags...)
-			continue
+		meta.alloc = field.Tag.Get("alloc")
+		switch meta.alloc {
+		case "", "false":
+			meta.alloc = ""
+		case "true", "optional":
+			if !nested || kind != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
+				return nil, fmt.Errorf("%s.%s: alloc tag requires a pointer to a struct", t, field.Name)
+			}
+		default:
+			return nil, fmt.Errorf("%s.%s: invalid alloc tag: %s", t, field.Name, meta.alloc)
 		}
 
-		if kind == reflect.Ptr {
-			//fmt.Println("pointer: "+field.Name, fValue)
-			if !fValue.IsNil() {
-				var ptrValue interface{} = fValue.Interface()
-				ptrFlags := extractFlags(ptrValue, prefix.Append(fPrefix))
-				flags = append(flags, ptrFlags...)
+		if nested && kind == reflect.Struct {
+			meta.kind = structField
+			fields = append(fields, meta)
+			continue
+		}
+		if kind == reflect.Ptr && !hasParser && field.Type.Elem().Kind() != reflect.Struct {
+			if _, found, _ := findParser(pm, "", field.Type.Elem()); !found && parserName == "" {
+				// not a flag, such as *[]string
+				continue
 			}
+			// an optional scalar, which is allocated when the flag is set
+			pType = field.Type.Elem()
+			meta.pType = pType
+			meta.kind = optionalField
+		} else if nested && kind == reflect.Ptr {
+			meta.kind = pointerField
+			fields = append(fields, meta)
+			continue
+		}
+		if nested && kind == reflect.Interface {
+			meta.kind = interfaceField
+			fields = append(fields, meta)
 			continue
 		}
 
-		if kind == reflect.Interface {
-			if !fValue.IsNil() {
-				var ptrValue interface{} = fValue.Interface()
-				ptrFlags := extractFlags(ptrValue, prefix.Append(fPrefix))
-				flags = append(flags, ptrFlags...)
+		meta.usage = field.Tag.Get("usage")
+		meta.env = field.Tag.Get("env")
+		meta.defaultUse = field.Tag.Get("default")
+		meta.initValue = field.Tag.Get("init")
+		if field.Tag.Get("count") == "true" {
+			if !isCountKind(kind) {
+				return nil, fmt.Errorf("%s.%s: count tag requires an integer field", t, field.Name)
 			}
+			meta.kind = counterField
+			fields = append(fields, meta)
 			continue
 		}
-
-		var cf commandFlag
-		cf.Usage = field.Tag.Get("usage")
-		cf.Prefix = prefix
-		parse, found := pm.Parser(pType)
+		parse, found, err := findParser(pm, parserName, pType)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		if found && kind == reflect.Map {
+			meta.parseKey, found, _ = findParser(pm, "", field.Type.Key())
+		}
 		if found {
+			meta.parse = parse
 			if kind == reflect.Slice {
-				sv := newSliceValue(&field, value.Field(i), parse)
-				sv.pType = pType
-				sv.DefaultUse = field.Tag.Get("default")
+				meta.kind = sliceField
+			} else if kind == reflect.Map {
+				meta.kind = mapField
+			} else if meta.kind != optionalField {
+				meta.kind = scalarField
+			}
+			fields = append(fields, meta)
+		}
+		// fields without a parser are not flags
+	}
+	return fields, nil
+}
+
+func extractFlagsV(value reflect.Value, t reflect.Type, prefix *flagPrefix, path string, pm reflx.ParserManager) ([]*commandFlag, error) {
+	fields, err := typeFlags(t, pm)
+	if err != nil {
+		return nil, err
+	}
+	var flags []*commandFlag
+	for _, meta := range fields {
+		fValue := value.Field(meta.index)
+		fPath := meta.name
+		if path != "" {
+			fPath = path + "." + meta.name
+		}
+		switch meta.kind {
+		case structField:
+			sFlags, err := extractFlagsV(fValue, meta.fType, prefix.Append(meta.copyPrefix()), fPath, pm)
+			if err != nil {
+				return nil, err
+			}
+			flags = append(flags, sFlags...)
+		case pointerField, interfaceField:
+			if fValue.IsNil() && meta.alloc != "" {
+				fValue.Set(reflect.New(meta.fType.Elem()))
+			}
+			if !fValue.IsNil() {
+				var ptrValue interface{} = fValue.Interface()
+				ptrFlags, err := extractPtrFlags(ptrValue, prefix.Append(meta.copyPrefix()), fPath, pm)
+				if err != nil {
+					return nil, err
+				}
+				if meta.alloc == "optional" && fValue.CanAddr() {
+					key := fValue.Addr().Interface()
+					for _, cf := range ptrFlags {
+						cf.Allocated = append(cf.Allocated, key)
+					}
+				}
+				flags = append(flags, ptrFlags...)
+			}
+		default:
+			cf := &commandFlag{
+				Names:     append([]string(nil), meta.names...),
+				Usage:     meta.usage,
+				Prefix:    prefix,
+				Type:      meta.fType,
+				Env:       meta.env,
+				Path:      fPath,
+				InitValue: meta.initValue,
+			}
+			if fValue.CanAddr() {
+				cf.Field = fValue.Addr().Interface()
+			}
+			if meta.kind == counterField {
+				cf.Value = &counterValue{Value: fValue}
+			} else if meta.kind == mapField {
+				cf.Value = &mapValue{Value: fValue, ParseKey: meta.parseKey, Parse: meta.parse, DefaultUse: meta.defaultUse}
+			} else if meta.kind == optionalField {
+				cf.Value = &optionalValue{Value: fValue, Parse: meta.parse, pType: meta.pType, DefaultUse: meta.defaultUse}
+			} else if meta.kind == sliceField {
+				sv := newSliceValue(fValue, meta.parse)
+				sv.pType = meta.pType
+				sv.DefaultUse = meta.defaultUse
 				cf.Value = sv
 			} else {
-				fv := &fieldValue{Value: value.Field(i), Parse: parse}
-				fv.pType = pType
-				fv.DefaultUse = field.Tag.Get("default")
+				fv := &fieldValue{Value: fValue, Parse: meta.parse}
+				fv.pType = meta.pType
+				fv.DefaultUse = meta.defaultUse
 				cf.Value = fv
 			}
-			cf.Names = names
-			flags = append(flags, &cf)
-		} else if len(names) > 0 || cf.Usage != "" {
-			fmt.Println("no parser for " + field.Type.String())
+			flags = append(flags, cf)
+		}
+	}
+	return flags, nil
+}
+
+// applyInitTags sets the flags that have an "init" tag and whose field is zero, to the value of the tag.
+// The value of a slice or map flag is split at commas, and each part is set separately.
+func applyInitTags(flags []*commandFlag) error {
+	for _, cf := range flags {
+		if cf.InitValue == "" || cf.Field == nil {
+			continue
+		}
+		field := reflect.ValueOf(cf.Field).Elem()
+		if !field.IsZero() {
+			continue
+		}
+		values := []string{cf.InitValue}
+		switch field.Kind() {
+		case reflect.Slice, reflect.Map:
+			values = strings.Split(cf.InitValue, ",")
+		}
+		for _, s := range values {
+			if err := cf.Value.Set(s); err != nil {
+				return fmt.Errorf("%s: init %q: %w", cf.Path, cf.InitValue, err)
+			}
 		}
 	}
-	return flags
+	return nil
 }
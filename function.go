@@ -13,15 +13,36 @@ func funcUsage(fn interface{}) string {
 	if fType.Kind() != reflect.Func {
 		return ""
 	}
-	n := fType.NumIn()
-	types := make([]string, n)
-	for i := 0; i < n; i++ {
-		typeName := fType.In(i).String()
-		types[i] = "<" + typeName + ">"
+	var types []string
+	for _, i := range argIndexes(fType) {
+		typeName := argTypeName(fType.In(i))
+		types = append(types, "<"+typeName+">")
 	}
 	return strings.Join(types, " ")
 }
 
+var stringsType = reflect.TypeOf([]string(nil))
+
+// isInjected checks if the i-th parameter of a function is provided by an injector, instead of the command arguments.
+// A parameter is injected if its type is an injected type, except for the variadic parameter.  See Inject.
+func isInjected(fType reflect.Type, i int) bool {
+	if fType.IsVariadic() && i == fType.NumIn()-1 {
+		return false
+	}
+	return isInjectedType(fType.In(i))
+}
+
+// argIndexes returns the indexes of the parameters of a function that are mapped to command arguments.
+func argIndexes(fType reflect.Type) []int {
+	var indexes []int
+	for i := 0; i < fType.NumIn(); i++ {
+		if !isInjected(fType, i) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
 func isFuncCompatible(fn interface{}) error {
 	fType := reflect.TypeOf(fn)
 	if fType.Kind() != reflect.Func {
@@ -37,14 +58,50 @@ func isFuncCompatible(fn interface{}) error {
 	return nil
 }
 
-func buildInputs(fn interface{}, args []string) ([]reflect.Value, error) {
+// buildInputs returns the input values of fn, with the injected values provided by inj,
+// and the other values converted from args.
+func buildInputs(fn interface{}, args []string, pm reflx.ParserManager, inj *injector) ([]reflect.Value, error) {
 	fType := reflect.TypeOf(fn)
+	indexes := argIndexes(fType)
+	values, err := parseArgs(fType, indexes, args, pm)
+	if err != nil {
+		return nil, err
+	}
 	numIn := fType.NumIn()
+	if len(indexes) == numIn {
+		return values, nil
+	}
+	in := make([]reflect.Value, 0, numIn+len(values))
+	k := 0
+	for i := 0; i < numIn; i++ {
+		if k < len(indexes) && indexes[k] == i {
+			if fType.IsVariadic() && i == numIn-1 {
+				in = append(in, values[k:]...)
+			} else {
+				in = append(in, values[k])
+			}
+			k++
+			continue
+		}
+		v, err := inj.value(fType.In(i))
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, v)
+	}
+	return in, nil
+}
+
+// parseArgs converts the command arguments to the types of the parameters of fType at the specified indexes.
+// If fType is variadic, the values of the variadic parameter are at the end.
+func parseArgs(fType reflect.Type, indexes []int, args []string, pm reflx.ParserManager) ([]reflect.Value, error) {
+	numIn := len(indexes)
+	variadic := fType.IsVariadic()
 	if numIn == 0 && len(args) > 0 {
 		return nil, errors.New("function takes no arguments")
 	}
-	if !fType.IsVariadic() {
-		if numIn == 1 && fType.In(0) == reflect.TypeOf(args) {
+	if !variadic {
+		if numIn == 1 && fType.In(indexes[0]) == stringsType {
 			// we make an exception for a function that takes a single []string argument
 			return []reflect.Value{reflect.ValueOf(args)}, nil
 		}
@@ -57,15 +114,14 @@ func buildInputs(fn interface{}, args []string) ([]reflect.Value, error) {
 		}
 	}
 	in := make([]reflect.Value, len(args))
-	pm := reflx.NewParserManager()
 	stringType := reflect.TypeOf("")
 	var aType reflect.Type
 	var parse reflx.ParseFunc
 	for i, arg := range args {
 		if i < numIn {
-			aType = fType.In(i)
+			aType = fType.In(indexes[i])
 		}
-		if i == numIn-1 && fType.IsVariadic() {
+		if i == numIn-1 && variadic {
 			aType = aType.Elem()
 		}
 		if aType == stringType {
@@ -73,7 +129,7 @@ func buildInputs(fn interface{}, args []string) ([]reflect.Value, error) {
 		} else {
 			if i < numIn {
 				var found bool
-				parse, found = pm.Parser(aType)
+				parse, found, _ = findParser(pm, "", aType)
 				if !found {
 					return nil, errors.New("no parser for " + aType.String())
 				}
@@ -97,22 +153,42 @@ func wrapFunc(fn interface{}) func([]string) error {
 		panic(err)
 	}
 	return func(args []string) error {
-		in, err := buildInputs(fn, args)
-		if err != nil {
-			return err
+		return invokeFunc(fn, args, DefaultParsers, newInjector(newInvocation(nil, args), nil))
+	}
+}
+
+// invokeFunc converts the arguments to the input types of fn, using pm, and calls fn.
+// Parameters that are not mapped to arguments are provided by inj.
+func invokeFunc(fn interface{}, args []string, pm reflx.ParserManager, inj *injector) (err error) {
+	var in []reflect.Value
+	in, err = buildInputs(fn, args, pm, inj)
+	if err != nil {
+		return err
+	}
+	err = openFiles(in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err2 := closeFiles(in); err == nil {
+			err = err2
 		}
-		result := reflect.ValueOf(fn).Call(in)
-		switch len(result) {
-		case 0:
+	}()
+	return callFunc(fn, in)
+}
+
+func callFunc(fn interface{}, in []reflect.Value) error {
+	result := reflect.ValueOf(fn).Call(in)
+	switch len(result) {
+	case 0:
+		return nil
+	case 1:
+		r := result[0]
+		if r.IsNil() {
 			return nil
-		case 1:
-			r := result[0]
-			if r.IsNil() {
-				return nil
-			}
-			return (result[0].Interface()).(error)
-		default:
-			return errors.New("function has more than one output")
 		}
+		return (result[0].Interface()).(error)
+	default:
+		return errors.New("function has more than one output")
 	}
 }
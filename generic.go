@@ -0,0 +1,98 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Arg is the constraint of the argument types of the generic run functions.
+// These are the types that the default parsers can parse, and file arguments.
+type Arg interface {
+	~string | ~bool |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64 | ~complex64 | ~complex128 |
+		*InputFile | *OutputFile
+}
+
+// The generic run functions are type-safe alternatives to RunFunc.
+// The compiler checks that the function signature is supported, instead of RunFunc panicking at program start.
+// The arguments are parsed in the same way as with RunFunc, using the parsers of the command.
+//
+// The RunAny functions accept arguments of any type, such as application types with their own parsers.
+// The compiler checks only the number of arguments, and they panic when the command is specified,
+// if an argument type has no parser, or if it is an injected type.
+
+// Run0 specifies a function that takes no arguments, to run when executing cmd.
+func Run0(cmd *SimpleCommand, fn func() error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// Run1 specifies a function that takes one argument, to run when executing cmd.
+func Run1[A Arg](cmd *SimpleCommand, fn func(A) error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// Run2 specifies a function that takes two arguments, to run when executing cmd.
+func Run2[A, B Arg](cmd *SimpleCommand, fn func(A, B) error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// Run3 specifies a function that takes three arguments, to run when executing cmd.
+func Run3[A, B, C Arg](cmd *SimpleCommand, fn func(A, B, C) error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// RunVariadic specifies a function that takes any number of arguments of the same type, to run when executing cmd.
+func RunVariadic[A Arg](cmd *SimpleCommand, fn func(...A) error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// RunArgs specifies a function that takes the command arguments as strings, to run when executing cmd.
+func RunArgs(cmd *SimpleCommand, fn func([]string) error) *SimpleCommand {
+	return cmd.RunFunc(fn)
+}
+
+// RunAny1 is like Run1, for an argument of any type that has a parser.
+// The parsers are those of cmd, or DefaultParsers, so call cmd.Parsers() first for application types.
+func RunAny1[A any](cmd *SimpleCommand, fn func(A) error) *SimpleCommand {
+	return cmd.checkArgTypes(fn).RunFunc(fn)
+}
+
+// RunAny2 is like Run2, for arguments of any type that has a parser.  See RunAny1.
+func RunAny2[A, B any](cmd *SimpleCommand, fn func(A, B) error) *SimpleCommand {
+	return cmd.checkArgTypes(fn).RunFunc(fn)
+}
+
+// RunAny3 is like Run3, for arguments of any type that has a parser.  See RunAny1.
+func RunAny3[A, B, C any](cmd *SimpleCommand, fn func(A, B, C) error) *SimpleCommand {
+	return cmd.checkArgTypes(fn).RunFunc(fn)
+}
+
+// RunAnyVariadic is like RunVariadic, for arguments of any type that has a parser.  See RunAny1.
+func RunAnyVariadic[A any](cmd *SimpleCommand, fn func(...A) error) *SimpleCommand {
+	return cmd.checkArgTypes(fn).RunFunc(fn)
+}
+
+// Flags specifies the flags struct of cmd, like SimpleCommand.Flags, but requires a pointer.
+func Flags[T any](cmd *SimpleCommand, flags *T) *SimpleCommand {
+	return cmd.Flags(flags)
+}
+
+// checkArgTypes panics if a parameter of fn is an injected type, or if there is no parser for it.
+func (t *SimpleCommand) checkArgTypes(fn interface{}) *SimpleCommand {
+	fType := reflect.TypeOf(fn)
+	for i := 0; i < fType.NumIn(); i++ {
+		if isInjected(fType, i) {
+			panic(fmt.Sprintf("argument %d: %v is an injected type", i+1, fType.In(i)))
+		}
+	}
+	pm := t.parserMgr
+	if pm == nil {
+		pm = DefaultParsers
+	}
+	if !canParseArgs(fType, pm) {
+		panic(fmt.Sprintf("no parser for the arguments of %v", fType))
+	}
+	return t
+}
@@ -1,5 +1,5 @@
 module melato.org/command
 
-go 1.16
+go 1.21
 
 require gopkg.in/yaml.v2 v2.4.0
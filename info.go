@@ -0,0 +1,145 @@
+package command
+
+import (
+	"reflect"
+)
+
+// FlagInfo describes a command flag, for documentation and tools.
+type FlagInfo struct {
+	// Name is the primary name of the flag, including any prefix.
+	Name string
+
+	// Aliases are the other names of the flag, including any prefix.
+	Aliases []string
+
+	// Usage is the description of the flag, including any prefix usage.
+	// It is taken from Usage.Flags, if it is there, otherwise from the "usage" tag.
+	Usage string
+
+	// Type is the type of the struct field of the flag.
+	Type reflect.Type
+
+	// Default is the value of the flag, before parsing the command line.
+	// It is a string, a number, a bool, or a slice of such values.
+	Default interface{}
+
+	// Env is the environment variable that sets the flag, if any.
+	Env string
+
+	// Field is a pointer to the struct field of the flag, in the copy of the flags that FlagInfo() uses.
+	Field interface{}
+}
+
+// FlagInfo returns information about the flags of the command, not including the flags of any ancestor commands.
+// It calls the Init() method of a shallow copy of the flags, if any, so that it does not modify the flags.
+// Init() should therefore not modify any structs that the flags point to.
+// For the same reason, "init" tags are applied only to the fields of the copy, and not to structs that it points to.
+//
+// FlagInfo uses the parsers of the command, or DefaultParsers, since it does not know the ancestors of the command.
+// Use AncestorFlagInfo for a command that inherits the parsers of its ancestors.
+func (t *SimpleCommand) FlagInfo() ([]*FlagInfo, error) {
+	return t.AncestorFlagInfo()
+}
+
+// AncestorFlagInfo is like FlagInfo, for a command whose ancestors are specified, starting from the root command.
+// It uses the same parsers as when the command runs, which may be specified by an ancestor command.
+func (t *SimpleCommand) AncestorFlagInfo(ancestors ...*SimpleCommand) ([]*FlagInfo, error) {
+	ci := createCommandInfo("", inspect(t))
+	for i := len(ancestors) - 1; i >= 0 && ci.parsers == nil; i-- {
+		ci.parsers = ancestors[i].parsers()
+	}
+	err := ci.Init()
+	if err != nil {
+		return nil, err
+	}
+	var result []*FlagInfo
+	for _, cf := range ci.Flags {
+		k := cf.PrimaryNameIndex()
+		info := &FlagInfo{
+			Name:    cf.Prefix.ComposeName(cf.Names[k]),
+			Usage:   ci.flagUsage(cf),
+			Type:    cf.Type,
+			Default: flagJsonValue(cf),
+			Env:     cf.Env,
+			Field:   cf.Field,
+		}
+		if info.Type == nil && cf.Field != nil {
+			// static flags do not have a type, see StaticFlags
+			info.Type = reflect.TypeOf(cf.Field).Elem()
+		}
+		for i, name := range cf.Names {
+			if i != k {
+				info.Aliases = append(info.Aliases, cf.Prefix.ComposeName(name))
+			}
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// ArgTypes returns the types of the arguments of the function specified by RunFunc(), and whether the function takes a variable number of arguments.
+// It does, if it is variadic, or if its only argument is a []string, which receives all the command arguments.
+// In that case, the last type is a slice type.
+// Injected parameters are not included.  See Inject.
+// It returns nil and false if the command does not have a run function specified by RunFunc().
+func (t *SimpleCommand) ArgTypes() ([]reflect.Type, bool) {
+	if t.runFunc == nil {
+		return nil, false
+	}
+	fType := reflect.TypeOf(t.runFunc)
+	types := []reflect.Type{}
+	for _, i := range argIndexes(fType) {
+		types = append(types, fType.In(i))
+	}
+	variadic := fType.IsVariadic() || (len(types) == 1 && types[0] == stringsType)
+	return types, variadic
+}
+
+// inspectedCommand is a command whose flags are a shallow copy of the flags of another command.
+// It is used to initialize and extract the flags of a command without running it, so that the flags are not modified.
+type inspectedCommand struct {
+	command
+	flagsCopy interface{}
+}
+
+func inspect(cmd command) *inspectedCommand {
+	c := &inspectedCommand{command: cmd, flagsCopy: cmd.flags()}
+	v := reflect.ValueOf(c.flagsCopy)
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+		p := reflect.New(v.Elem().Type())
+		p.Elem().Set(v.Elem())
+		c.flagsCopy = p.Interface()
+	}
+	return c
+}
+
+// ownFlags returns the flags whose fields are in the copy of the flags, and not in structs that the copy points to.
+func (t *inspectedCommand) ownFlags(flags []*commandFlag) []*commandFlag {
+	v := reflect.ValueOf(t.flagsCopy)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	start := v.Pointer()
+	end := start + v.Elem().Type().Size()
+	var result []*commandFlag
+	for _, cf := range flags {
+		if cf.Field == nil {
+			continue
+		}
+		if p := reflect.ValueOf(cf.Field).Pointer(); p >= start && p < end {
+			result = append(result, cf)
+		}
+	}
+	return result
+}
+
+func (t *inspectedCommand) flags() interface{} {
+	return t.flagsCopy
+}
+
+func (t *inspectedCommand) init() error {
+	if f, ok := t.flagsCopy.(Init); ok {
+		return f.Init()
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+// CommandPath contains the names of the commands from the root command to the command that runs.
+// A run function can receive it by having a parameter of this type.
+type CommandPath []string
+
+// provider creates an injected value.
+type provider func(inv *Invocation) (reflect.Value, error)
+
+// Provide registers a value that is injected into the run functions of cmd and its descendant commands,
+// for each parameter of type T.  T is typically a pointer or an interface type.
+// It also registers T as an injected type, with Inject.
+//
+// A run function, specified with RunFunc(), may have parameters that are not command arguments.
+// A parameter is injected if its type is an injected type.  Its value is, in order of precedence:
+//   - A value registered with Provide or ProvideFunc for the command or its nearest ancestor.
+//   - The flags of the command or of an ancestor command, if they have the same type as the parameter.
+//   - For context.Context, the Context of the Invocation.
+//   - For io.Writer, the Stdout of the Invocation.
+//   - For *log.Logger, log.Default().
+//   - For *slog.Logger, slog.Default().  See LogFlags.
+//   - For CommandPath, the path of the command.
+//   - For *Invocation, the Invocation.
+//
+// It is an error if there is no value for an injected parameter.
+func Provide[T any](cmd *SimpleCommand, value T) *SimpleCommand {
+	v := reflect.ValueOf(&value).Elem()
+	registerInjected(v.Type())
+	return cmd.provide(v.Type(), func(inv *Invocation) (reflect.Value, error) {
+		return v, nil
+	})
+}
+
+// ProvideFunc registers a function that creates a value that is injected into the run functions of cmd and its descendant commands,
+// for each parameter of type T.  The function is called when a run function needs the value.
+// See Provide.
+func ProvideFunc[T any](cmd *SimpleCommand, fn func(inv *Invocation) (T, error)) *SimpleCommand {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	registerInjected(t)
+	return cmd.provide(t, func(inv *Invocation) (reflect.Value, error) {
+		value, err := fn(inv)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&value).Elem(), nil
+	})
+}
+
+var (
+	contextType    = reflect.TypeOf((*context.Context)(nil)).Elem()
+	writerType     = reflect.TypeOf((*io.Writer)(nil)).Elem()
+	loggerType     = reflect.TypeOf((*log.Logger)(nil))
+	slogType       = reflect.TypeOf((*slog.Logger)(nil))
+	pathType       = reflect.TypeOf(CommandPath(nil))
+	invocationType = reflect.TypeOf((*Invocation)(nil))
+)
+
+// injectedTypes contains the types of the run function parameters that are injected, instead of converted from command arguments.
+var injectedTypes = map[reflect.Type]bool{
+	contextType:    true,
+	writerType:     true,
+	loggerType:     true,
+	slogType:       true,
+	pathType:       true,
+	invocationType: true,
+}
+
+var injectLock sync.Mutex
+
+// Inject registers T as an injected type.  Run function parameters of type T are injected, as described in Provide,
+// instead of being converted from command arguments.
+// Provide, ProvideFunc, and SimpleCommand.Flags() register their types.
+// The built-in injected types are context.Context, io.Writer, *log.Logger, *slog.Logger, CommandPath and *Invocation.
+//
+// A parameter whose type is not an injected type is a command argument, and it is an error if there is no parser for its type.
+func Inject[T any]() {
+	registerInjected(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func registerInjected(t reflect.Type) {
+	injectLock.Lock()
+	defer injectLock.Unlock()
+	injectedTypes[t] = true
+}
+
+func isInjectedType(t reflect.Type) bool {
+	injectLock.Lock()
+	defer injectLock.Unlock()
+	return injectedTypes[t]
+}
+
+// injector provides the injected parameters of a run function.
+type injector struct {
+	inv *Invocation
+	// providers contains the providers of the commands, from the command that runs to the root command
+	providers []map[reflect.Type]provider
+}
+
+func newInjector(inv *Invocation, ancestors []*commandInfo) *injector {
+	inj := &injector{inv: inv}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if p := ancestors[i].Command.providers(); p != nil {
+			inj.providers = append(inj.providers, p)
+		}
+	}
+	return inj
+}
+
+func (t *injector) value(vType reflect.Type) (reflect.Value, error) {
+	for _, providers := range t.providers {
+		if p, found := providers[vType]; found {
+			return p(t.inv)
+		}
+	}
+	for i := len(t.inv.Flags) - 1; i >= 0; i-- {
+		if flags := t.inv.Flags[i]; flags != nil && reflect.TypeOf(flags) == vType {
+			return reflect.ValueOf(flags), nil
+		}
+	}
+	var v interface{}
+	switch vType {
+	case contextType:
+		v = t.inv.Context
+	case writerType:
+		v = t.inv.Stdout
+	case loggerType:
+		v = log.Default()
+	case slogType:
+		v = slog.Default()
+	case pathType:
+		v = CommandPath(t.inv.Path)
+	case invocationType:
+		v = t.inv
+	default:
+		return reflect.Value{}, fmt.Errorf("no value for parameter of type %s", vType)
+	}
+	value := reflect.New(vType).Elem()
+	value.Set(reflect.ValueOf(v))
+	return value, nil
+}
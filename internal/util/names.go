@@ -0,0 +1,24 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FlagName creates a flag name from a Go field or method name, e.g. "DryRun" -> "dry-run"
+func FlagName(name string) string {
+	var buf strings.Builder
+	lastUpper := true
+	for _, c := range name {
+		upper := unicode.IsUpper(c)
+		if upper {
+			c = unicode.ToLower(c)
+		}
+		if !lastUpper && upper {
+			buf.WriteString("-")
+		}
+		lastUpper = upper
+		buf.WriteString(string(c))
+	}
+	return buf.String()
+}
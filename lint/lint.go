@@ -0,0 +1,162 @@
+// Package lint checks a command tree for usage problems,
+// such as missing descriptions, conflicting flags, or examples that refer to nonexistent commands or flags.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"melato.org/command"
+)
+
+// Kinds of findings
+const (
+	MissingShort       = "missing-short"        // a command has no short description
+	MissingFlagUsage   = "missing-flag-usage"   // a flag has no usage
+	DuplicateFlag      = "duplicate-flag"       // a flag has the same name as a flag of an ancestor command
+	ConflictingAlias   = "conflicting-alias"    // two flags of the same command have a common name
+	InvalidExample     = "invalid-example"      // an example cannot be split into arguments, or has invalid flag values or arguments
+	UnknownExampleCmd  = "unknown-example-cmd"  // an example refers to a nonexistent subcommand
+	UnknownExampleFlag = "unknown-example-flag" // an example refers to a nonexistent flag
+	FlagError          = "flag-error"           // the flags of a command could not be initialized
+)
+
+// Finding is a problem found in a command tree.
+type Finding struct {
+	// Path contains the command names from the root command to the command with the problem.
+	Path []string
+
+	// Kind is one of the kinds of findings, e.g. MissingShort.
+	Kind string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (t Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", strings.Join(t.Path, " "), t.Kind, t.Message)
+}
+
+// level contains the flags of a command in a command path.
+type level struct {
+	name  string
+	cmd   *command.SimpleCommand
+	flags map[string]*command.FlagInfo
+}
+
+type checker struct {
+	findings []Finding
+}
+
+func (t *checker) add(path []*level, kind string, format string, args ...interface{}) {
+	names := make([]string, len(path))
+	for i, l := range path {
+		names[i] = l.name
+	}
+	t.findings = append(t.findings, Finding{Path: names, Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check checks a command tree and returns the findings, sorted by command path.
+// name is the name of the root command.
+func Check(cmd *command.SimpleCommand, name string) []Finding {
+	var c checker
+	c.check(nil, name, cmd)
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return comparePaths(c.findings[i].Path, c.findings[j].Path) < 0
+	})
+	return c.findings
+}
+
+// comparePaths compares command paths element by element, so that a command precedes its subcommands.
+func comparePaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+func (t *checker) check(ancestors []*level, name string, cmd *command.SimpleCommand) {
+	l := &level{name: name, cmd: cmd, flags: make(map[string]*command.FlagInfo)}
+	path := append(append([]*level(nil), ancestors...), l)
+	if cmd.Usage.Short == "" {
+		t.add(path, MissingShort, "missing short description")
+	}
+	parents := make([]*command.SimpleCommand, len(ancestors))
+	for i, a := range ancestors {
+		parents[i] = a.cmd
+	}
+	flags, err := cmd.AncestorFlagInfo(parents...)
+	var conflict *command.FlagConflictError
+	if errors.As(err, &conflict) {
+		t.add(path, ConflictingAlias, "%v", err)
+	} else if err != nil {
+		t.add(path, FlagError, "%v", err)
+	}
+	for _, f := range flags {
+		if f.Usage == "" {
+			t.add(path, MissingFlagUsage, "flag %s has no usage", f.Name)
+		}
+		for _, name := range append([]string{f.Name}, f.Aliases...) {
+			if other, exists := l.flags[name]; exists {
+				t.add(path, ConflictingAlias, "flags %s and %s have the same name: %s", other.Name, f.Name, name)
+				continue
+			}
+			l.flags[name] = f
+			for _, a := range ancestors {
+				if _, exists := a.flags[name]; exists {
+					t.add(path, DuplicateFlag, "flag %s is also a flag of %s", name, a.name)
+				}
+			}
+		}
+	}
+	for _, example := range cmd.Usage.Examples {
+		t.checkExample(path, example)
+	}
+	commands := cmd.Commands()
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t.check(path, name, commands[name])
+	}
+}
+
+// checkExample checks that an example can be parsed against the command tree.
+// Examples are relative to the root command.
+func (t *checker) checkExample(path []*level, example string) {
+	err := command.CheckExample(path[0].cmd, example)
+	switch {
+	case err == nil:
+	case errors.Is(err, command.ErrUnknownCommand):
+		t.add(path, UnknownExampleCmd, "%s: %v", example, err)
+	case errors.Is(err, command.ErrUnknownFlag):
+		t.add(path, UnknownExampleFlag, "%s: %v", example, err)
+	default:
+		t.add(path, InvalidExample, "%s: %v", example, err)
+	}
+}
+
+// TB is the part of testing.TB that Test uses, so that this package does not depend on the testing package.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Test reports each finding of Check() as a test error.
+// It is meant to be called from a test of the application, e.g.:
+//
+//	func TestUsage(t *testing.T) {
+//		lint.Test(t, buildCommand(), "myapp")
+//	}
+func Test(t TB, cmd *command.SimpleCommand, name string) {
+	t.Helper()
+	for _, f := range Check(cmd, name) {
+		t.Errorf("%s", f)
+	}
+}
@@ -0,0 +1,39 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"melato.org/command"
+)
+
+type rootFlags struct {
+	Verbose bool   `name:"v" usage:"verbose"`
+	Output  string `name:"o"`
+}
+
+type subFlags struct {
+	V bool   `name:"v" usage:"another v"`
+	A string `name:"a" usage:"a"`
+}
+
+type badFlags struct {
+	A string `name:"a,all" usage:"a"`
+	B string `name:"b,all" usage:"b"`
+}
+
+func TestCheck(t *testing.T) {
+	var cmd command.SimpleCommand
+	cmd.Short("root").Flags(&rootFlags{})
+	cmd.Example("-v -o x sub -a 1 arg").Example("sub -x").Example("other").Example("sub")
+	cmd.Command("sub").Short("sub").Flags(&subFlags{}).RunFunc(func(s string) {})
+	cmd.Command("bad").Flags(&badFlags{})
+	var kinds []string
+	for _, f := range Check(&cmd, "app") {
+		kinds = append(kinds, f.Kind)
+	}
+	expected := []string{MissingFlagUsage, UnknownExampleFlag, UnknownExampleCmd, InvalidExample, MissingShort, ConflictingAlias, DuplicateFlag}
+	if strings.Join(kinds, " ") != strings.Join(expected, " ") {
+		t.Errorf("%v", Check(&cmd, "app"))
+	}
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LevelTrace is the log level that is enabled by -v -v.
+const LevelTrace = slog.LevelDebug - 4
+
+// LogFlags are standard logging flags, which configure a slog.Logger.
+// They are meant to be used by a root command, either as its flags, or embedded in its flags struct:
+//
+//	type App struct {
+//		command.LogFlags
+//		...
+//	}
+//
+// If the embedding struct has its own Configured() or Close() method, it should call the LogFlags method.
+//
+// Configured() creates the logger and makes it the default slog logger,
+// so that it is used by the descendant commands, and it is injected into run functions that have a *slog.Logger parameter.
+// Setting the default slog logger also redirects the output of the standard log package, and clears its flags.
+// Close() restores the previous default logger, and the output and flags of the log package.
+type LogFlags struct {
+	LogLevel  string `name:"log-level" usage:"log level: debug, info, warn, or error" default:"info"`
+	LogFormat string `name:"log-format" usage:"log format: text or json" default:"text"`
+	LogFile   string `name:"log-file" usage:"log file, instead of stderr"`
+	Verbose   int    `name:"v" usage:"lower the log level, -v for debug, -vv for trace" count:"true"`
+
+	logger   *slog.Logger
+	previous *slog.Logger
+	logOut   io.Writer // the output of the log package, before Configured()
+	logFlags int       // the flags of the log package, before Configured()
+	file     *os.File
+}
+
+// Level returns the log level specified by the flags.
+func (t *LogFlags) Level() (slog.Level, error) {
+	level := slog.LevelInfo
+	if t.LogLevel != "" {
+		if strings.EqualFold(t.LogLevel, "trace") {
+			level = LevelTrace
+		} else if err := level.UnmarshalText([]byte(t.LogLevel)); err != nil {
+			return level, err
+		}
+	}
+	level -= slog.Level(4 * t.Verbose)
+	return level, nil
+}
+
+func (t *LogFlags) Configured() error {
+	level, err := t.Level()
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stderr
+	if t.LogFile != "" {
+		t.file, err = os.OpenFile(t.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		w = t.file
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch t.LogFormat {
+	case "", "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format: %s", t.LogFormat)
+	}
+	t.logger = slog.New(handler)
+	if t.previous == nil {
+		t.previous = slog.Default()
+		t.logOut = log.Writer()
+		t.logFlags = log.Flags()
+	}
+	slog.SetDefault(t.logger)
+	return nil
+}
+
+// Logger returns the logger that was created by Configured(), or slog.Default() if Configured() has not been called.
+func (t *LogFlags) Logger() *slog.Logger {
+	if t.logger == nil {
+		return slog.Default()
+	}
+	return t.logger
+}
+
+// Close restores the default slog logger and the log package settings that were in effect before Configured(),
+// and closes the log file, if any.
+func (t *LogFlags) Close() error {
+	if t.previous != nil {
+		slog.SetDefault(t.previous)
+		log.SetOutput(t.logOut)
+		log.SetFlags(t.logFlags)
+		t.previous = nil
+		t.logOut = nil
+	}
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Messages contains the text that the framework shows in the command help and in errors.
+// It can be changed with SetMessages(), in order to localize the help.
+type Messages struct {
+	Usage             string `yaml:"usage,omitempty"`              // heading of the command-line usage
+	Arguments         string `yaml:"arguments,omitempty"`          // heading of the argument descriptions
+	Examples          string `yaml:"examples,omitempty"`           // heading of the examples
+	GlobalOptions     string `yaml:"global_options,omitempty"`     // heading of the root command options
+	Options           string `yaml:"options,omitempty"`            // heading of the options of the command that runs
+	CommandOptions    string `yaml:"command_options,omitempty"`    // heading of the options of an intermediate command, with a %s for the command name
+	AvailableCommands string `yaml:"available_commands,omitempty"` // heading of the subcommand list
+	Plugin            string `yaml:"plugin,omitempty"`             // description of plugin subcommands
+	SameAs            string `yaml:"same_as,omitempty"`            // usage of flag aliases, with a %s for the primary flag name
+	Repeated          string `yaml:"repeated,omitempty"`           // appended to the usage of counter flags
+	Help              string `yaml:"help,omitempty"`               // usage of the help flag
+	DryRun            string `yaml:"dry_run,omitempty"`            // usage of the dry-run flag
+	NoSuchCommand     string `yaml:"no_such_command,omitempty"`    // error for an unknown subcommand, with a %s for the command name
+	UsageError        string `yaml:"usage_error,omitempty"`        // error for wrong usage, with a %s for the command usage
+	WrongUsage        string `yaml:"wrong_usage,omitempty"`        // error for wrong usage, when the command has no usage
+}
+
+// DefaultMessages returns the default (English) messages.
+func DefaultMessages() Messages {
+	return Messages{
+		Usage:             "Usage:",
+		Arguments:         "Arguments:",
+		Examples:          "Examples:",
+		GlobalOptions:     "Global Options",
+		Options:           "Options",
+		CommandOptions:    "%s Options",
+		AvailableCommands: "Available Commands:",
+		Plugin:            "(plugin)",
+		SameAs:            "same as --%s",
+		Repeated:          "(may be repeated)",
+		Help:              "help",
+		DryRun:            "print the command, its flags and arguments, without running it",
+		NoSuchCommand:     "no such command: %s",
+		UsageError:        "usage: %s",
+		WrongUsage:        "wrong usage",
+	}
+}
+
+var messages = DefaultMessages()
+
+// SetMessages replaces the framework messages with the non-empty fields of m.
+// The messages that have a %s for a value must have exactly one %s, and no other verbs, except %%.
+// It returns an error for any message that does not, and it does not replace it.
+func SetMessages(m Messages) error {
+	var errs []error
+	set := func(s *string, value string) {
+		if value != "" {
+			*s = value
+		}
+	}
+	setFormat := func(s *string, value string, key string) {
+		if value == "" {
+			return
+		}
+		if err := checkFormat(value); err != nil {
+			errs = append(errs, fmt.Errorf("message %s: %q: %w", key, value, err))
+			return
+		}
+		*s = value
+	}
+	set(&messages.Usage, m.Usage)
+	set(&messages.Arguments, m.Arguments)
+	set(&messages.Examples, m.Examples)
+	set(&messages.GlobalOptions, m.GlobalOptions)
+	set(&messages.Options, m.Options)
+	setFormat(&messages.CommandOptions, m.CommandOptions, "command_options")
+	set(&messages.AvailableCommands, m.AvailableCommands)
+	set(&messages.Plugin, m.Plugin)
+	setFormat(&messages.SameAs, m.SameAs, "same_as")
+	set(&messages.Repeated, m.Repeated)
+	set(&messages.Help, m.Help)
+	set(&messages.DryRun, m.DryRun)
+	setFormat(&messages.NoSuchCommand, m.NoSuchCommand, "no_such_command")
+	setFormat(&messages.UsageError, m.UsageError, "usage_error")
+	set(&messages.WrongUsage, m.WrongUsage)
+	return errors.Join(errs...)
+}
+
+// checkFormat checks that a message has exactly one %s verb, and no other verbs except %%.
+func checkFormat(s string) error {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(s) {
+			return errors.New("trailing %")
+		}
+		switch s[i] {
+		case '%':
+		case 's':
+			n++
+		default:
+			return fmt.Errorf("unsupported verb %%%c", s[i])
+		}
+	}
+	if n != 1 {
+		return errors.New("expected one %s")
+	}
+	return nil
+}
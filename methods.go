@@ -0,0 +1,110 @@
+package command
+
+import (
+	"reflect"
+
+	"melato.org/command/reflx"
+)
+
+// MethodUsage is an optional interface for objects passed to SimpleCommand.Methods().
+type MethodUsage interface {
+	// Usage returns the usage of the commands created from methods.
+	// The map key is either the method name, e.g. "DryRun", or the command name, e.g. "dry-run".
+	Usage() map[string]Usage
+}
+
+// methods of the flags interfaces, which are never used as commands
+var lifecycleMethods = map[string]bool{
+	"Init":       true,
+	"Configured": true,
+	"Close":      true,
+	"Usage":      true,
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// isRunMethod checks if a method type can be used with RunFunc.
+func isRunMethod(fType reflect.Type) bool {
+	switch fType.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return fType.Out(0) == errorType
+	default:
+		return false
+	}
+}
+
+// canParseArgs checks if there are parsers for the argument types of a method type.
+func canParseArgs(fType reflect.Type, pm reflx.ParserManager) bool {
+	indexes := argIndexes(fType)
+	if len(indexes) == 1 && !fType.IsVariadic() && fType.In(indexes[0]) == stringsType {
+		return true
+	}
+	for _, i := range indexes {
+		aType := fType.In(i)
+		if fType.IsVariadic() && i == fType.NumIn()-1 {
+			aType = aType.Elem()
+		}
+		if aType == reflect.TypeOf("") {
+			continue
+		}
+		if _, found, _ := findParser(pm, "", aType); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Methods adds a subcommand for each exported method of obj, using RunFunc().
+// The name of each subcommand is derived from the method name, in the same way as flag names, e.g. "DryRun" -> "dry-run".
+//
+// Methods whose names are in exclude are skipped.  An excluded name may be either a method name or a command name.
+// The methods Init, Configured, Close, and Usage are always skipped,
+// and so are methods that return anything other than nothing or a single error,
+// and methods with argument types that have no parser.
+// The parsers are those of this command, or DefaultParsers, so call Parsers() before Methods() if the methods use application types.
+//
+// If obj implements MethodUsage, its usage is applied to the subcommands.
+// Usage can also be applied from a companion YAML file, using the usage package.
+//
+// obj is not used for flags.  Use Flags(obj) for that.
+func (t *SimpleCommand) Methods(obj interface{}, exclude ...string) *SimpleCommand {
+	excluded := make(map[string]bool)
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	var usage map[string]Usage
+	if u, ok := obj.(MethodUsage); ok {
+		usage = u.Usage()
+	}
+	pm := t.parserMgr
+	if pm == nil {
+		pm = DefaultParsers
+	}
+	v := reflect.ValueOf(obj)
+	vType := v.Type()
+	for i := 0; i < vType.NumMethod(); i++ {
+		method := vType.Method(i)
+		name := createFlagName(method.Name)
+		if lifecycleMethods[method.Name] || excluded[method.Name] || excluded[name] {
+			continue
+		}
+		fn := v.Method(i)
+		if !isRunMethod(fn.Type()) || !canParseArgs(fn.Type(), pm) {
+			continue
+		}
+		cmd := t.Command(name).RunFunc(fn.Interface())
+		u, found := usage[method.Name]
+		if !found {
+			u, found = usage[name]
+		}
+		if found {
+			if u.Use == "" {
+				u.Use = cmd.Usage.Use
+			}
+			cmd.Usage = u
+		}
+	}
+	return t
+}
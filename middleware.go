@@ -0,0 +1,69 @@
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Invocation describes a command that is about to run.
+// It is passed to Middleware.
+type Invocation struct {
+	// Path contains the names of the commands from the root command to the command that runs.
+	Path []string
+
+	// Flags contains the flags objects of the commands in Path, in the same order.
+	// An element is nil if the corresponding command has no flags.
+	// The flags have been parsed when the middleware is called.
+	Flags []interface{}
+
+	// Args are the command arguments that remain after parsing the flags.
+	// Middleware may modify them before calling next.
+	Args []string
+
+	// DryRun is true if the command will not run, because a dry-run flag was set.
+	// See SimpleCommand.DryRunFlag
+	DryRun bool
+
+	// Context is injected into run functions that have a context.Context parameter.
+	// It is context.Background(), unless middleware replaces it, e.g. with a context that has a deadline.
+	Context context.Context
+
+	// Stdout is injected into run functions that have an io.Writer parameter.
+	// It is os.Stdout, unless middleware replaces it.
+	Stdout io.Writer
+}
+
+// Middleware wraps the execution of a command.
+// It should call next() to continue the execution, and normally return the error that next() returns.
+// It may also return without calling next(), in order to prevent the command from running.
+type Middleware func(inv *Invocation, next func() error) error
+
+func newInvocation(ancestors []*commandInfo, args []string) *Invocation {
+	inv := &Invocation{Args: args, Context: context.Background(), Stdout: os.Stdout}
+	for _, a := range ancestors {
+		inv.Path = append(inv.Path, a.Name)
+		inv.Flags = append(inv.Flags, a.Command.flags())
+		if a.dryRun {
+			inv.DryRun = true
+		}
+	}
+	return inv
+}
+
+// chainMiddleware composes the middleware of the ancestors around fn,
+// so that the root command's middleware is the outermost.
+func chainMiddleware(inv *Invocation, ancestors []*commandInfo, fn func() error) func() error {
+	next := fn
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		wrappers := ancestors[i].Command.wrappers()
+		for j := len(wrappers) - 1; j >= 0; j-- {
+			m := wrappers[j]
+			inner := next
+			next = func() error {
+				return m(inv, inner)
+			}
+		}
+	}
+	return next
+}
@@ -1,8 +1,11 @@
 package command
 
 import (
+	"sort"
 	"strings"
 	"unicode"
+
+	"melato.org/command/internal/util"
 )
 
 func isExported(name string) bool {
@@ -16,20 +19,7 @@ func isExported(name string) bool {
 }
 
 func createFlagName(name string) string {
-	var buf strings.Builder
-	lastUpper := true
-	for _, c := range name {
-		upper := unicode.IsUpper(c)
-		if upper {
-			c = unicode.ToLower(c)
-		}
-		if !lastUpper && upper {
-			buf.WriteString("-")
-		}
-		lastUpper = upper
-		buf.WriteString(string(c))
-	}
-	return buf.String()
+	return util.FlagName(name)
 }
 
 func quote(s string) string {
@@ -56,3 +46,13 @@ func quote(s string) string {
 	buf.WriteString(`"`)
 	return buf.String()
 }
+
+// sortedNames returns the names of the commands, sorted.
+func sortedNames(commands map[string]*SimpleCommand) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// PanicError is the error returned when a command panics.
+// The panic is recovered after calling Close() for the flags of all commands in the command chain.
+// Main prints its Stack, unless it was written to a crash report.
+type PanicError struct {
+	// Value is the value that was passed to panic()
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked
+	Stack []byte
+
+	// Path contains the names of the commands from the root command to the command that panicked.
+	Path []string
+
+	// Args are the command arguments, after parsing flags.
+	Args []string
+
+	// Report is the crash report file, if one was written.
+	Report string
+}
+
+func (t *PanicError) Error() string {
+	msg := fmt.Sprintf("panic: %v", t.Value)
+	if t.Report != "" {
+		msg += "\ncrash report: " + t.Report
+	}
+	return msg
+}
+
+// Unwrap returns the panic value, if it is an error.
+func (t *PanicError) Unwrap() error {
+	err, _ := t.Value.(error)
+	return err
+}
+
+// recoverPanic converts a panic to a *PanicError, and assigns it to *err.
+// It must be deferred directly, so that recover() can stop the panic.
+func recoverPanic(inv *Invocation, ancestors []*commandInfo, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e := &PanicError{Value: r, Stack: debug.Stack(), Path: inv.Path, Args: inv.Args}
+	for _, a := range ancestors {
+		if dir, ok := a.Command.crashReportDir(); ok {
+			file, err2 := e.writeReport(dir)
+			if err2 != nil {
+				ErrorPrintf("%v\n", err2)
+			} else {
+				e.Report = file
+			}
+			break
+		}
+	}
+	*err = e
+}
+
+func (t *PanicError) writeReport(dir string) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	name := "crash"
+	if len(t.Path) > 0 {
+		name = t.Path[0] + "-crash"
+	}
+	f, err := os.CreateTemp(dir, name+"-"+time.Now().Format("20060102-150405")+"-*.txt")
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(f, "command: %s\n", strings.Join(t.Path, " "))
+	fmt.Fprintf(f, "args: %s\n", strings.Join(t.Args, " "))
+	fmt.Fprintf(f, "os.Args: %s\n", strings.Join(os.Args, " "))
+	fmt.Fprintf(f, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(f, "panic: %v\n\n", t.Value)
+	f.Write(t.Stack)
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+	file, err := filepath.Abs(f.Name())
+	if err != nil {
+		return f.Name(), nil
+	}
+	return file, nil
+}
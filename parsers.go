@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+
+	"melato.org/command/reflx"
+)
+
+// DefaultParsers is the package-level parser registry.
+// It is used for the flags and the run function arguments of commands that do not specify their own parsers with SimpleCommand.Parsers().
+//
+// Applications may add parsers for their own types with DefaultParsers.SetParser(),
+// or named parsers, which are selected by the "parser" tag of a flag field, with DefaultParsers.SetNamedParser().
+// The flag metadata of struct types is cached for each ParserManager.  Parsers that are added after a command runs are used
+// only with a ParserManager that has a Version() method that changes when parsers are added, such as reflx.Parsers.
+//
+// Named parsers can be used with any ParserManager that also implements reflx.NamedParserManager.
+var DefaultParsers = reflx.NewParserManager()
+
+// findParser returns the parser for a flag or argument type, or for a parser name.
+// If a name is specified, the parser must exist.
+func findParser(pm reflx.ParserManager, name string, t reflect.Type) (reflx.ParseFunc, bool, error) {
+	if name != "" {
+		var parse reflx.ParseFunc
+		found := false
+		if named, ok := pm.(reflx.NamedParserManager); ok {
+			parse, found = named.NamedParser(name)
+		}
+		if !found {
+			return nil, false, fmt.Errorf("no parser named %s", name)
+		}
+		return parse, true, nil
+	}
+	if parse, found := pm.Parser(t); found {
+		return parse, true, nil
+	}
+	switch t {
+	case inputFileType:
+		return parseInputFile, true, nil
+	case outputFileType:
+		return parseOutputFile, true, nil
+	}
+	return nil, false, nil
+}
+
+// inheritParsers sets the parsers of a command that does not have its own parsers, to the parsers of its parent command.
+func inheritParsers(ci *commandInfo, ancestors []*commandInfo) {
+	if ci.parsers == nil {
+		if len(ancestors) > 0 {
+			ci.parsers = ancestors[len(ancestors)-1].parsers
+		} else {
+			ci.parsers = DefaultParsers
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"melato.org/command/reflx"
+)
+
+// ExitError is an error that specifies the exit code of the program.
+// Main exits with this code, without printing the error.
+// It is returned when an external plugin command exits with a non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (t *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(t.Code)
+}
+
+// pluginPrefix returns the executable name prefix for plugins of cmd, and whether plugins are enabled.
+// The default prefix is the command path, joined with "-".
+func pluginPrefix(cmd command, ancestors []*commandInfo) (string, bool) {
+	prefix, enabled := cmd.plugins()
+	if !enabled {
+		return "", false
+	}
+	if prefix == "" {
+		names := make([]string, len(ancestors))
+		for i, a := range ancestors {
+			names[i] = a.Name
+		}
+		prefix = strings.Join(names, "-")
+	}
+	return prefix, true
+}
+
+// lookPlugin finds the executable for the plugin subcommand name.
+func lookPlugin(prefix, name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return "", false
+	}
+	path, err := exec.LookPath(prefix + "-" + name)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// findPlugins lists the plugin executables in PATH, by subcommand name.
+// If the same plugin appears in more than one directory, the first one is used, as with exec.LookPath.
+// Relative directories in PATH are skipped, since exec.LookPath does not find executables in them.
+func findPlugins(prefix string) map[string]string {
+	plugins := make(map[string]string)
+	prefix += "-"
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			// exec.LookPath does not use relative directories, including the empty directory
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			file := entry.Name()
+			if !strings.HasPrefix(file, prefix) || entry.IsDir() {
+				continue
+			}
+			name := file[len(prefix):]
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else {
+				info, err := entry.Info()
+				if err != nil || info.Mode().Perm()&0111 == 0 {
+					continue
+				}
+			}
+			if name == "" {
+				continue
+			}
+			if _, exists := plugins[name]; !exists {
+				plugins[name] = filepath.Join(dir, file)
+			}
+		}
+	}
+	return plugins
+}
+
+// pluginCommand runs an external plugin executable, in the command chain of the command that found it.
+// Its other methods are those of that command, so that its middleware, Configured() and Close() methods are called.
+type pluginCommand struct {
+	command
+	name string
+	path string
+}
+
+func (t *pluginCommand) run(args []string, pm reflx.ParserManager, inj *injector) error {
+	return runPlugin(t.path, args)
+}
+
+func (t *pluginCommand) decodeArgs(args []string, pm reflx.ParserManager) ([]reflect.Value, error) {
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		values[i] = reflect.ValueOf(arg)
+	}
+	return values, nil
+}
+
+// runPlugin runs an external plugin executable, with the remaining arguments.
+// The plugin inherits the environment and the standard input and output.
+func runPlugin(path string, args []string) error {
+	c := exec.Command(path, args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitCode(exitErr)}
+	}
+	return err
+}
+
+// exitCode returns the exit code of a process, or 128 + the signal number if it was killed by a signal, as shells do.
+func exitCode(err *exec.ExitError) int {
+	type signaled interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	}
+	if status, ok := err.Sys().(signaled); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return err.ExitCode()
+}
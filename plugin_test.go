@@ -0,0 +1,108 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePlugin creates an executable shell script in dir.
+func writePlugin(t *testing.T, dir, name, script string) {
+	err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pluginDir(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin tests use shell scripts")
+	}
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestPlugin(t *testing.T) {
+	dir := pluginDir(t)
+	out := filepath.Join(dir, "out.txt")
+	writePlugin(t, dir, "app-hello", `echo "$@" > `+out)
+	var root SimpleCommand
+	var flags closeFlags
+	var path []string
+	root.Flags(&flags).Plugins("").Wrap(func(inv *Invocation, next func() error) error {
+		path = inv.Path
+		return next()
+	})
+	root.Command("sub").RunFunc(func() {})
+	err := runCommand("app", &root, []string{"hello", "a", "b"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(string(data)); s != "a b" {
+		t.Errorf("plugin args: %s", s)
+	}
+	if !reflect.DeepEqual(path, []string{"app", "hello"}) {
+		t.Errorf("path: %v", path)
+	}
+	if !flags.closed {
+		t.Errorf("Close() was not called")
+	}
+	plugins := findPlugins("app")
+	if plugins["hello"] != filepath.Join(dir, "app-hello") {
+		t.Errorf("plugins: %v", plugins)
+	}
+	// relative and empty PATH directories are not searched
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	t.Setenv("PATH", "."+string(filepath.ListSeparator))
+	if plugins := findPlugins("app"); len(plugins) != 0 {
+		t.Errorf("plugins: %v", plugins)
+	}
+}
+
+func TestPluginExitCode(t *testing.T) {
+	dir := pluginDir(t)
+	writePlugin(t, dir, "app-fail", "exit 3")
+	writePlugin(t, dir, "app-kill", "kill -9 $$")
+	var root SimpleCommand
+	root.Plugins("")
+	for name, code := range map[string]int{"fail": 3, "kill": 128 + 9} {
+		err := runCommand("app", &root, []string{name}, nil)
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.Code != code {
+			t.Errorf("%s: expected exit code %d, got %v", name, code, err)
+		}
+	}
+}
+
+func TestPluginRunFunc(t *testing.T) {
+	dir := pluginDir(t)
+	writePlugin(t, dir, "app-hello", "exit 0")
+	var root SimpleCommand
+	var args []string
+	root.Plugins("").RunFunc(func(a ...string) {
+		args = a
+	})
+	err := runCommand("app", &root, []string{"other", "x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"other", "x"}) {
+		t.Errorf("args: %v", args)
+	}
+}
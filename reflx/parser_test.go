@@ -18,3 +18,21 @@ func TestParser(t *testing.T) {
 		t.Errorf("expected: %s actual: %s", "x1", b.X)
 	}
 }
+
+func TestParseHex(t *testing.T) {
+	var i int8
+	var u uint16
+	iv := reflect.ValueOf(&i).Elem()
+	uv := reflect.ValueOf(&u).Elem()
+	for s, expected := range map[string]int8{"0x10": 16, "-0x10": -16, "+10": 16, "-80": -128} {
+		if err := ParseHex(iv, s); err != nil || i != expected {
+			t.Errorf("%s: expected %d, got %d %v", s, expected, i, err)
+		}
+	}
+	if err := ParseHex(uv, "0XfF"); err != nil || u != 255 {
+		t.Errorf("expected 255, got %d %v", u, err)
+	}
+	if err := ParseHex(uv, "-0x1"); err == nil {
+		t.Errorf("expected an error for a negative unsigned value")
+	}
+}
@@ -1,6 +1,7 @@
 package reflx
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -15,13 +16,25 @@ type ParserManager interface {
 	SetParser(t reflect.Type, f ParseFunc)
 }
 
-type parserManager struct {
-	typeParsers map[reflect.Type]ParseFunc
-	kindParsers map[reflect.Kind]ParseFunc
+// NamedParserManager is an optional interface of a ParserManager, for parsers that are selected by name instead of by type.
+type NamedParserManager interface {
+	// NamedParser - Get a parser by name, e.g. "hex"
+	NamedParser(name string) (ParseFunc, bool)
+
+	// SetNamedParser - define a parser that is selected by name instead of by type
+	SetNamedParser(name string, f ParseFunc)
+}
+
+// Parsers is the ParserManager created by NewParserManager.  It also implements NamedParserManager.
+type Parsers struct {
+	version      int
+	typeParsers  map[reflect.Type]ParseFunc
+	kindParsers  map[reflect.Kind]ParseFunc
+	namedParsers map[string]ParseFunc
 }
 
-func NewParserManager() ParserManager {
-	var mgr parserManager
+func NewParserManager() *Parsers {
+	var mgr Parsers
 	mgr.typeParsers = make(map[reflect.Type]ParseFunc)
 	mgr.kindParsers = make(map[reflect.Kind]ParseFunc)
 	mgr.kindParsers[reflect.String] = ParseString
@@ -40,14 +53,22 @@ func NewParserManager() ParserManager {
 	mgr.kindParsers[reflect.Complex64] = ParseComplex
 	mgr.kindParsers[reflect.Complex128] = ParseComplex
 	mgr.kindParsers[reflect.Bool] = ParseBool
+	mgr.namedParsers = make(map[string]ParseFunc)
+	mgr.namedParsers["hex"] = ParseHex
 	return &mgr
 }
 
-func (mgr *parserManager) SetParser(t reflect.Type, f ParseFunc) {
+func (mgr *Parsers) SetParser(t reflect.Type, f ParseFunc) {
 	mgr.typeParsers[t] = f
+	mgr.version++
 }
 
-func (mgr *parserManager) Parser(t reflect.Type) (ParseFunc, bool) {
+// Version returns a number that changes whenever a parser is set, so that information that depends on the parsers can be cached.
+func (mgr *Parsers) Version() int {
+	return mgr.version
+}
+
+func (mgr *Parsers) Parser(t reflect.Type) (ParseFunc, bool) {
 	parse, found := mgr.typeParsers[t]
 	if found {
 		return parse, true
@@ -55,3 +76,31 @@ func (mgr *parserManager) Parser(t reflect.Type) (ParseFunc, bool) {
 	parse, found = mgr.kindParsers[t.Kind()]
 	return parse, found
 }
+
+func (mgr *Parsers) SetNamedParser(name string, f ParseFunc) {
+	mgr.namedParsers[name] = f
+	mgr.version++
+}
+
+func (mgr *Parsers) NamedParser(name string) (ParseFunc, bool) {
+	parse, found := mgr.namedParsers[name]
+	return parse, found
+}
+
+// Parse - parse s and set the result to value, using the named parser, or the parser for the type of value if name is empty
+func (mgr *Parsers) Parse(name string, value reflect.Value, s string) error {
+	var parse ParseFunc
+	var found bool
+	if name != "" {
+		parse, found = mgr.NamedParser(name)
+		if !found {
+			return fmt.Errorf("no parser named %s", name)
+		}
+	} else {
+		parse, found = mgr.Parser(value.Type())
+		if !found {
+			return fmt.Errorf("no parser for %s", value.Type())
+		}
+	}
+	return parse(value, s)
+}
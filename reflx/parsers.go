@@ -1,8 +1,10 @@
 package reflx
 
 import (
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 func ParseString(value reflect.Value, s string) error {
@@ -54,3 +56,33 @@ func ParseComplex(value reflect.Value, s string) error {
 	value.SetComplex(v)
 	return nil
 }
+
+// ParseHex parses a hexadecimal integer, with an optional sign and an optional "0x" prefix, e.g. "-0x10", for signed or unsigned integer values.
+// Negative values are accepted only for signed integers.
+func ParseHex(value reflect.Value, s string) error {
+	var sign string
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(sign+digits, 16, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if sign == "-" {
+			return fmt.Errorf("negative value for %s: %s", value.Type(), sign+s)
+		}
+		v, err := strconv.ParseUint(digits, 16, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(v)
+	default:
+		return fmt.Errorf("cannot parse hex into %s", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Package schema exports the command model as JSON Schema,
+// so that the same definitions that are used by the command line can be used to generate forms,
+// or to validate automation requests.
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"melato.org/command"
+)
+
+// Draft is the JSON Schema version of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema that is used to describe commands.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	PrefixItems          []*Schema          `json:"prefixItems,omitempty"`
+	Items                interface{}        `json:"items,omitempty"` // *Schema or false
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var inputFileType = reflect.TypeOf((*command.InputFile)(nil))
+var outputFileType = reflect.TypeOf((*command.OutputFile)(nil))
+
+// TypeSchema returns the schema for a flag or argument type.
+func TypeSchema(t reflect.Type) *Schema {
+	switch t {
+	case inputFileType:
+		return &Schema{Type: "string", Format: "input-file"}
+	case outputFileType:
+		return &Schema{Type: "string", Format: "output-file"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Complex64, reflect.Complex128:
+		return &Schema{Type: "string", Format: "complex"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: TypeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Ptr:
+		return TypeSchema(t.Elem())
+	}
+	return &Schema{}
+}
+
+// Command returns the schema of a single command, not including its subcommands.
+// The schema describes an object with two properties:
+//   - "flags" is an object with a property for each flag of the command, by flag name.
+//   - "args" is an array of the command arguments, if the command has a run function specified by RunFunc().
+//
+// The flags of ancestor commands are described by the schemas of the ancestor commands.
+func Command(cmd *command.SimpleCommand, path []string) (*Schema, error) {
+	return commandSchema(cmd, path, nil)
+}
+
+// commandSchema returns the schema of a command, using the parsers that it inherits from its ancestors.
+func commandSchema(cmd *command.SimpleCommand, path []string, ancestors []*command.SimpleCommand) (*Schema, error) {
+	s := &Schema{
+		Schema:      Draft,
+		Title:       strings.Join(path, " "),
+		Description: description(cmd.Usage),
+		Type:        "object",
+		Properties:  make(map[string]*Schema),
+	}
+	flags, err := cmd.AncestorFlagInfo(ancestors...)
+	if err != nil {
+		return nil, err
+	}
+	fs := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: boolPtr(false)}
+	for _, f := range flags {
+		p := TypeSchema(f.Type)
+		p.Description = f.Usage
+		p.Default = f.Default
+		fs.Properties[f.Name] = p
+		for _, alias := range f.Aliases {
+			fs.Properties[alias] = &Schema{Description: "same as " + f.Name, Type: p.Type, Items: p.Items}
+		}
+	}
+	s.Properties["flags"] = fs
+	if types, variadic := cmd.ArgTypes(); types != nil {
+		args := &Schema{Type: "array"}
+		n := len(types)
+		if variadic {
+			n--
+			args.Items = TypeSchema(types[n].Elem())
+		} else {
+			args.Items = false
+			args.MaxItems = intPtr(n)
+		}
+		for _, t := range types[:n] {
+			args.PrefixItems = append(args.PrefixItems, TypeSchema(t))
+		}
+		args.MinItems = intPtr(n)
+		if cmd.Usage.Use != "" {
+			args.Description = cmd.Usage.Use
+		}
+		s.Properties["args"] = args
+	}
+	return s, nil
+}
+
+// Commands returns the schemas of a command and all its descendant commands,
+// by command path, with the command names separated by spaces.
+// name is the name of the root command.
+func Commands(cmd *command.SimpleCommand, name string) (map[string]*Schema, error) {
+	schemas := make(map[string]*Schema)
+	err := addCommands(schemas, cmd, []string{name}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+func addCommands(schemas map[string]*Schema, cmd *command.SimpleCommand, path []string, ancestors []*command.SimpleCommand) error {
+	s, err := commandSchema(cmd, path, ancestors)
+	if err != nil {
+		return err
+	}
+	schemas[s.Title] = s
+	commands := cmd.Commands()
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], cmd)
+	for _, name := range names {
+		subPath := append(append([]string(nil), path...), name)
+		err := addCommands(schemas, commands[name], subPath, ancestors)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes the schemas of a command tree to w, as a JSON object, by command path.
+func Write(w io.Writer, cmd *command.SimpleCommand, name string) error {
+	schemas, err := Commands(cmd, name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(schemas)
+}
+
+func description(u command.Usage) string {
+	if u.Long == "" {
+		return u.Short
+	}
+	if u.Short == "" {
+		return u.Long
+	}
+	return u.Short + "\n\n" + u.Long
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"melato.org/command"
+	"melato.org/command/reflx"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+type testFlags struct {
+	Name  string   `name:"name,n" usage:"the name"`
+	Count int      `name:"count" usage:"the count"`
+	Tags  []string `name:"tag" usage:"tags"`
+}
+
+func (t *testFlags) Init() error {
+	t.Count = 1
+	return nil
+}
+
+// checkGolden compares the schema of cmd with testdata/<name>.json
+func checkGolden(t *testing.T, name string, cmd *command.SimpleCommand) {
+	s, err := Command(cmd, []string{"app", name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	file := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("%s does not match:\n%s", file, data)
+	}
+}
+
+func TestScalarArgs(t *testing.T) {
+	var cmd command.SimpleCommand
+	cmd.Short("scalar").Flags(&testFlags{}).RunFunc(func(s string, n int, b bool) {})
+	checkGolden(t, "scalar", &cmd)
+}
+
+func TestVariadicArgs(t *testing.T) {
+	var cmd command.SimpleCommand
+	cmd.RunFunc(func(s string, n ...float64) {})
+	checkGolden(t, "variadic", &cmd)
+}
+
+func TestSliceArgs(t *testing.T) {
+	var cmd command.SimpleCommand
+	cmd.RunFunc(func(args []string) {})
+	checkGolden(t, "slice", &cmd)
+}
+
+type point struct {
+	X, Y int
+}
+
+type pointFlags struct {
+	P point `name:"p" usage:"a point"`
+}
+
+func TestInheritedParsers(t *testing.T) {
+	pm := reflx.NewParserManager()
+	pm.SetParser(reflect.TypeOf(point{}), func(v reflect.Value, s string) error {
+		var p point
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		v.Set(reflect.ValueOf(p))
+		return err
+	})
+	var root command.SimpleCommand
+	root.Parsers(pm).Command("sub").Flags(&pointFlags{})
+	schemas, err := Commands(&root, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := schemas["app sub"].Properties["flags"].Properties
+	if len(flags) != 1 || flags["p"] == nil {
+		t.Errorf("flags: %v", flags)
+	}
+}
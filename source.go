@@ -0,0 +1,144 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// FlagSource specifies where the value of a flag came from.
+type FlagSource int
+
+const (
+	// SourceDefault means that the value was not set from any external source.
+	// It is the zero value of the field, or a value set by Init().
+	SourceDefault FlagSource = iota
+	// SourceEnv means that the value was set from an environment variable, specified by the "env" tag.
+	SourceEnv
+	// SourceConfig means that the value was set from a configuration file.
+	// The framework does not read configuration files, so the application should record this source with SetSource().
+	SourceConfig
+	// SourceArgument means that the value was set from the command line.
+	SourceArgument
+)
+
+func (t FlagSource) String() string {
+	switch t {
+	case SourceDefault:
+		return "default"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	case SourceArgument:
+		return "command line"
+	default:
+		return fmt.Sprintf("FlagSource(%d)", int(t))
+	}
+}
+
+var sourcesLock sync.Mutex
+
+// flagSources maps pointers to flag fields to the source of their value.
+var flagSources = make(map[interface{}]FlagSource)
+
+// SourceOf returns the source of the value of a flag field.
+// field is a pointer to a field of a flags struct, e.g. &t.Timeout.
+// It can be called from Configured() or from the run method.
+// The sources of all fields are reset when a root command starts running.
+// It returns SourceDefault for any field whose value was not set from an external source.
+func SourceOf(field interface{}) FlagSource {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	return flagSources[field]
+}
+
+// IsSet returns true if the value of a flag field was set from the command line, the environment, or a configuration file.
+// field is a pointer to a field of a flags struct, e.g. &t.Timeout.
+func IsSet(field interface{}) bool {
+	return SourceOf(field) != SourceDefault
+}
+
+// SetSource records the source of the value of a flag field.
+// field is a pointer to a field of a flags struct, e.g. &t.Timeout.
+// It is meant for applications that set flag values from other sources, such as a configuration file,
+// typically in the Init() method of the flags.
+func SetSource(field interface{}, source FlagSource) {
+	if field == nil {
+		return
+	}
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	flagSources[field] = source
+}
+
+// resetSources forgets the sources of all fields, so that a flags struct can be used again in another run.
+func resetSources() {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	flagSources = make(map[interface{}]FlagSource)
+}
+
+// unsetter is implemented by flag values whose first Set() replaces the value, instead of adding to it, such as slices and maps.
+type unsetter interface {
+	// unset makes the next Set() replace the value.
+	unset()
+}
+
+// applyEnv sets the flags that have an "env" tag from the corresponding environment variables.
+func applyEnv(flags []*commandFlag) error {
+	for _, cf := range flags {
+		if cf.Env == "" {
+			continue
+		}
+		s, found := os.LookupEnv(cf.Env)
+		if !found {
+			continue
+		}
+		if err := cf.Value.Set(s); err != nil {
+			return fmt.Errorf("$%s: %w", cf.Env, err)
+		}
+		// the command line replaces the environment value
+		if v, ok := cf.Value.(unsetter); ok {
+			v.unset()
+		}
+		cf.set = true
+		SetSource(cf.Field, SourceEnv)
+	}
+	return nil
+}
+
+// markArguments records the source of the flags that were set in the command line.
+func markArguments(fs *flag.FlagSet, flags []*commandFlag) {
+	byName := flagNames(flags)
+	fs.Visit(func(f *flag.Flag) {
+		if cf, found := byName[f.Name]; found {
+			cf.set = true
+			SetSource(cf.Field, SourceArgument)
+		}
+	})
+}
+
+// resetAllocated resets to nil the struct pointers with an alloc:"optional" tag, whose flags were not set in this run,
+// from the command line or the environment.
+func resetAllocated(flags []*commandFlag) {
+	var keys []interface{}
+	set := make(map[interface{}]bool)
+	for _, cf := range flags {
+		for _, key := range cf.Allocated {
+			isSet, seen := set[key]
+			if !seen {
+				keys = append(keys, key)
+			}
+			set[key] = isSet || cf.set
+		}
+	}
+	for _, key := range keys {
+		if !set[key] {
+			v := reflect.ValueOf(key).Elem()
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+}
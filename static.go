@@ -0,0 +1,118 @@
+package command
+
+import (
+	"flag"
+)
+
+// StaticFlags is implemented by flags structs that have generated flag bindings.
+// The flags of such a struct are taken from CommandFlags(), instead of from its fields by reflection.
+// See cmd/command-gen, which generates CommandFlags() methods.
+type StaticFlags interface {
+	CommandFlags() []*StaticFlag
+}
+
+// StaticFlag is a flag binding created by generated code.
+type StaticFlag struct {
+	Names []string
+	// Prefix is the name prefix of the flags of a nested struct, e.g. "server" for "server.port".
+	Prefix string
+	// Usage includes the usage prefix of any nested struct.
+	Usage string
+	Env   string
+	// Path is the path of the struct field, e.g. "Sub.X"
+	Path  string
+	Field interface{} // pointer to the struct field
+	Value flag.Value
+	// Init is the value of the "init" tag, which initializes the field before Init(), if the field is zero,
+	// or after Init(), if Init() created the field.
+	Init string
+	// Allocated contains pointers to the enclosing struct pointer fields that have an alloc:"optional" tag, e.g. &t.Sub
+	Allocated []interface{}
+}
+
+// StaticValue is a flag.Value that accesses a struct field through functions, without reflection.
+type StaticValue struct {
+	// Get formats the value of the field.
+	Get func() string
+	// SetFunc parses a string and sets the field, or appends to it, for a slice.
+	SetFunc func(s string) error
+	// Reset is called before the first Set(), for slices and maps, in order to discard the default value.
+	Reset func()
+	// Bool specifies a boolean flag, which does not need a value.
+	Bool bool
+	// Counter specifies a counter flag, which can be repeated, e.g. -vvv.  It is also a Bool flag.
+	Counter bool
+	// Optional specifies a pointer field, which is nil unless the flag is set.  Get returns "" for nil.
+	Optional bool
+	// Quote specifies that the value is quoted in usage.
+	Quote bool
+	// DefaultUse is the default value shown in usage, from the "default" tag.
+	DefaultUse string
+
+	isSet bool
+}
+
+func (t *StaticValue) IsBoolFlag() bool {
+	return t.Bool
+}
+
+func (t *StaticValue) isCounter() bool {
+	return t.Counter
+}
+
+func (t *StaticValue) String() string {
+	if t.Get == nil {
+		// the zero value, used by the flag package
+		return ""
+	}
+	s := t.Get()
+	if t.Optional && s == "" {
+		return t.DefaultUse
+	}
+	if t.Reset != nil {
+		if (s == "[]" || s == "map[]") && t.DefaultUse != "" {
+			return t.DefaultUse
+		}
+		return s
+	}
+	if s == "" {
+		if t.DefaultUse != "" {
+			return t.DefaultUse
+		}
+		return `""`
+	} else if t.Quote {
+		return quote(s)
+	}
+	return s
+}
+
+func (t *StaticValue) Set(s string) error {
+	if t.Reset != nil && !t.isSet {
+		t.Reset()
+		t.isSet = true
+	}
+	return t.SetFunc(s)
+}
+
+func (t *StaticValue) unset() {
+	t.isSet = false
+}
+
+// staticFlags converts the generated flags of a StaticFlags to commandFlags.
+func staticFlags(flags StaticFlags) ([]*commandFlag, error) {
+	var result []*commandFlag
+	for _, f := range flags.CommandFlags() {
+		result = append(result, &commandFlag{
+			Names:     f.Names,
+			Prefix:    &flagPrefix{Name: f.Prefix},
+			Usage:     f.Usage,
+			Value:     f.Value,
+			Env:       f.Env,
+			Field:     f.Field,
+			Path:      f.Path,
+			Allocated: f.Allocated,
+			InitValue: f.Init,
+		})
+	}
+	return result, checkFlagNames(result)
+}
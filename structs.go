@@ -0,0 +1,74 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// StructCommand creates a command tree from a struct, as an alternative to building it with Command().
+// v must be a pointer to a struct, which is used as the flags of the command, as with Flags(v).
+//
+// Each exported field with a "cmd" tag is a subcommand, and it must be a struct or a pointer to a struct.
+// A nil pointer is allocated.  The subcommand is created recursively from the field, in the same way.
+// The "cmd" tag specifies the name of the subcommand.  If it is empty, a name is created from the field name, as for flags.
+// The optional "short", "long", and "use" tags specify the usage of the subcommand.
+//
+// Fields with a "cmd" tag are not flags.  All other exported fields are flags, as with Flags(),
+// and the Init, Configured, and Closer interfaces are used in the same way.
+//
+// If the struct pointer has a Run method, it is used with RunFunc().
+//
+// StructCommand panics if v is not a pointer to a struct, or a "cmd" field is not a struct or a pointer to a struct,
+// or a Run method is not compatible with RunFunc().
+func StructCommand(v interface{}) *SimpleCommand {
+	cmd := &SimpleCommand{}
+	cmd.structCommand(reflect.ValueOf(v))
+	return cmd
+}
+
+func (t *SimpleCommand) structCommand(v reflect.Value) {
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("not a pointer to a struct: %v", v.Type()))
+	}
+	t.Flags(v.Interface())
+	if run := v.MethodByName("Run"); run.IsValid() {
+		if !isRunMethod(run.Type()) {
+			panic(fmt.Sprintf("%v.Run has unsupported signature %v: it must return nothing or an error", v.Type(), run.Type()))
+		}
+		t.RunFunc(run.Interface())
+	}
+	sv := v.Elem()
+	sType := sv.Type()
+	for i := 0; i < sType.NumField(); i++ {
+		field := sType.Field(i)
+		name, isCommand := field.Tag.Lookup("cmd")
+		if !isCommand || !isExported(field.Name) {
+			continue
+		}
+		if name == "" {
+			name = createFlagName(field.Name)
+		}
+		fv := sv.Field(i)
+		switch fv.Kind() {
+		case reflect.Struct:
+			fv = fv.Addr()
+		case reflect.Ptr:
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+		default:
+			panic(fmt.Sprintf("command field %s.%s is not a struct", sType, field.Name))
+		}
+		sub := t.Command(name)
+		sub.structCommand(fv)
+		if short := field.Tag.Get("short"); short != "" {
+			sub.Short(short)
+		}
+		if long := field.Tag.Get("long"); long != "" {
+			sub.Long(long)
+		}
+		if use := field.Tag.Get("use"); use != "" {
+			sub.Use(use)
+		}
+	}
+}
@@ -13,4 +13,10 @@ type Usage struct {
 
 	// Examples of command line invocation
 	Examples []string `yaml:"examples,omitempty"`
+
+	// Descriptions of flags, by flag name, including any prefix.  They override the "usage" tag of the flags.
+	Flags map[string]string `yaml:"flags,omitempty"`
+
+	// Descriptions of the command arguments, in order, shown in the help for a single command
+	Args []string `yaml:"args,omitempty"`
 }
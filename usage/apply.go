@@ -4,6 +4,7 @@ package usage
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"melato.org/command"
@@ -13,16 +14,37 @@ import (
 type Usage struct {
 	command.Usage `yaml:",inline"`
 	Commands      map[string]*Usage `yaml:"commands,omitempty"`
+
+	// Messages replaces the framework messages, for localization.  It is used only in the top-level usage.
+	Messages *command.Messages `yaml:"messages,omitempty"`
 }
 
 type CommandApplicator struct {
-	// Diff warns about differences between usage tree and command tree, to os.Stderr
+	// Diff warns about differences between usage tree and command tree, to Output
 	Diff bool
+	// Output receives the warnings and errors.  If it is nil, os.Stderr is used.
+	Output io.Writer
+}
+
+func (t *CommandApplicator) output() io.Writer {
+	if t.Output == nil {
+		return os.Stderr
+	}
+	return t.Output
 }
 
 // Apply copies the usage to the command, recursively.
 // Only non-empty fields are copied.
 func (t *CommandApplicator) Apply(cmd *command.SimpleCommand, u *Usage) {
+	if u.Messages != nil {
+		if err := command.SetMessages(*u.Messages); err != nil {
+			fmt.Fprintln(t.output(), err)
+		}
+	}
+	t.apply(cmd, u)
+}
+
+func (t *CommandApplicator) apply(cmd *command.SimpleCommand, u *Usage) {
 	if u.Short != "" {
 		cmd.Short(u.Short)
 	}
@@ -35,25 +57,67 @@ func (t *CommandApplicator) Apply(cmd *command.SimpleCommand, u *Usage) {
 	if len(u.Examples) > 0 {
 		cmd.Usage.Examples = u.Examples
 	}
+	if len(u.Flags) > 0 {
+		if cmd.Usage.Flags == nil {
+			cmd.Usage.Flags = make(map[string]string)
+		}
+		for name, text := range u.Flags {
+			cmd.Usage.Flags[name] = text
+		}
+	}
+	if len(u.Args) > 0 {
+		cmd.Usage.Args = u.Args
+	}
+	if t.Diff {
+		t.diffFlags(cmd, u)
+	}
 	commands := cmd.Commands()
 	for name, c := range u.Commands {
 		cmd, found := commands[name]
 		if found {
-			t.Apply(cmd, c)
+			t.apply(cmd, c)
 		} else if t.Diff {
-			fmt.Fprintf(os.Stderr, "extraneous usage command: %s\n", name)
+			fmt.Fprintf(t.output(), "extraneous usage command: %s\n", name)
 		}
 	}
 	if t.Diff {
 		for name, _ := range cmd.Commands() {
 			_, found := u.Commands[name]
 			if !found {
-				fmt.Fprintf(os.Stderr, "missing usage for command: %s\n", name)
+				fmt.Fprintf(t.output(), "missing usage for command: %s\n", name)
 			}
 		}
 	}
 }
 
+// diffFlags warns about flags that are in the usage but not in the command flags, and vice versa.
+func (t *CommandApplicator) diffFlags(cmd *command.SimpleCommand, u *Usage) {
+	flags, err := cmd.FlagInfo()
+	if err != nil {
+		fmt.Fprintln(t.output(), err)
+		return
+	}
+	names := make(map[string]bool)
+	for _, f := range flags {
+		_, found := u.Flags[f.Name]
+		names[f.Name] = true
+		for _, alias := range f.Aliases {
+			names[alias] = true
+			if _, hasAlias := u.Flags[alias]; hasAlias {
+				found = true
+			}
+		}
+		if !found {
+			fmt.Fprintf(t.output(), "missing usage for flag: %s\n", f.Name)
+		}
+	}
+	for name := range u.Flags {
+		if !names[name] {
+			fmt.Fprintf(t.output(), "extraneous usage flag: %s\n", name)
+		}
+	}
+}
+
 // ApplyEnv looks for a file specified in an environment variable,
 // reads this file, if it exists, reads this file and calls ApplyYaml with its content
 // Returns true if it found usage data without errors.
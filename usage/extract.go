@@ -10,9 +10,18 @@ import (
 // Extract copies the usage fields from a command.SimpleCommand, recursively.
 // It can be used to generate an external usage file from hardcoded usage strings,
 // so you can then remove the hardcoded usage and replace it with the yaml file.
+// The flag usage includes the "usage" tags of the command flags.
 func Extract(cmd *command.SimpleCommand) Usage {
 	var u Usage
 	u.Usage = cmd.Usage
+	if flags, err := cmd.FlagInfo(); err == nil && len(flags) > 0 {
+		u.Flags = make(map[string]string)
+		for _, f := range flags {
+			if f.Usage != "" {
+				u.Flags[f.Name] = f.Usage
+			}
+		}
+	}
 	for name, sub := range cmd.Commands() {
 		if u.Commands == nil {
 			u.Commands = make(map[string]*Usage)
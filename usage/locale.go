@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"melato.org/command"
+)
+
+// Locales returns the preferred locales of the user, from the most specific to the least specific,
+// using the first non-empty environment variable of LC_ALL, LC_MESSAGES, LANG.
+// For example, "de_DE.UTF-8" results in ["de_DE", "de"].
+// The "C" and "POSIX" locales result in no locales.
+func Locales() []string {
+	var locale string
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale = os.Getenv(name)
+		if locale != "" {
+			break
+		}
+	}
+	return parseLocale(locale)
+}
+
+func parseLocale(locale string) []string {
+	// remove the codeset and modifier, e.g. ".UTF-8", "@euro"
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	locales := []string{locale}
+	if i := strings.IndexAny(locale, "_-"); i > 0 {
+		locales = append(locales, locale[:i])
+	}
+	return locales
+}
+
+// LocaleFiles returns the names of the localized versions of a usage file, for the given locales,
+// from the most specific to the least specific.
+// For example, for "usage.yaml" and ["de_DE", "de"] it returns ["usage.de_DE.yaml", "usage.de.yaml"].
+func LocaleFiles(name string, locales []string) []string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	files := make([]string, len(locales))
+	for i, locale := range locales {
+		files[i] = base + "." + locale + ext
+	}
+	return files
+}
+
+// ApplyLocale applies usage files from fsys, such as an embed.FS, for the user's locale.
+// name is the default usage file, e.g. "usage.yaml".
+// It applies the default file first, and then any localized files that exist, from the least specific to the most specific,
+// e.g. "usage.yaml", "usage.de.yaml", "usage.de_DE.yaml".
+// Anything missing from a localized file falls back to the less specific files.
+//
+// Localized files can also contain framework messages, such as "Available Commands", under a top-level "messages" key.
+// See command.Messages
+//
+// It prints any errors to stderr.
+func ApplyLocale(cmd *command.SimpleCommand, fsys fs.FS, name string) {
+	files := LocaleFiles(name, Locales())
+	files = append(files, name)
+	applicator := &CommandApplicator{}
+	for i := len(files) - 1; i >= 0; i-- {
+		data, err := fs.ReadFile(fsys, files[i])
+		if err != nil {
+			if i == len(files)-1 {
+				// the default file should exist
+				fmt.Fprintln(os.Stderr, err)
+			}
+			continue
+		}
+		ApplyYaml(func(u Usage) { applicator.Apply(cmd, &u) }, data)
+	}
+}
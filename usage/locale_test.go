@@ -0,0 +1,34 @@
+package usage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := map[string][]string{
+		"de_DE.UTF-8": {"de_DE", "de"},
+		"fr@euro":     {"fr"},
+		"pt-BR":       {"pt-BR", "pt"},
+		"C":           nil,
+		"POSIX":       nil,
+		"C.UTF-8":     nil,
+		"":            nil,
+	}
+	for locale, expected := range tests {
+		if actual := parseLocale(locale); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: expected %v, actual %v", locale, expected, actual)
+		}
+	}
+}
+
+func TestLocaleFiles(t *testing.T) {
+	actual := LocaleFiles("usage/usage.yaml", []string{"de_DE", "de"})
+	expected := []string{"usage/usage.de_DE.yaml", "usage/usage.de.yaml"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+	if files := LocaleFiles("usage.yaml", nil); len(files) != 0 {
+		t.Errorf("%v", files)
+	}
+}
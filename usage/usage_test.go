@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"melato.org/command"
+)
+
+type testFlags struct {
+	Name  string `name:"name,n" usage:"the name"`
+	Count int    `name:"count" usage:"the count"`
+	inits int
+}
+
+func (t *testFlags) Init() error {
+	t.inits++
+	return nil
+}
+
+// newCommand creates a command tree, with or without usage.
+func newCommand(flags *testFlags, withUsage bool) *command.SimpleCommand {
+	var cmd command.SimpleCommand
+	cmd.Flags(flags)
+	sub := cmd.Command("sub").RunFunc(func(s string) {})
+	if withUsage {
+		cmd.Short("root").Long("the root command").Example("sub x")
+		sub.Short("sub").Use("<s>")
+		sub.Usage.Args = []string{"s: a string"}
+	}
+	return &cmd
+}
+
+func TestExtractApply(t *testing.T) {
+	var flags testFlags
+	u := Extract(newCommand(&flags, true))
+	if flags.inits != 0 {
+		t.Errorf("Extract called Init() of the flags")
+	}
+	// round trip through yaml
+	data, err := yaml.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := newCommand(&testFlags{}, false)
+	if !ApplyYaml(func(u Usage) { (&CommandApplicator{}).Apply(cmd, &u) }, data) {
+		t.Fatalf("cannot apply:\n%s", data)
+	}
+	expected := Extract(newCommand(&testFlags{}, true))
+	actual := Extract(cmd)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %+v\nactual: %+v", expected, actual)
+	}
+	if cmd.Usage.Flags["count"] != "the count" {
+		t.Errorf("flags: %v", cmd.Usage.Flags)
+	}
+	sub := cmd.Commands()["sub"]
+	if sub.Usage.Short != "sub" || sub.Usage.Use != "<s>" || len(sub.Usage.Args) != 1 {
+		t.Errorf("sub: %+v", sub.Usage)
+	}
+}
+
+func TestApplyDiff(t *testing.T) {
+	var flags testFlags
+	cmd := newCommand(&flags, false)
+	u := Usage{}
+	u.Flags = map[string]string{"count": "c", "size": "s"}
+	var out strings.Builder
+	(&CommandApplicator{Diff: true, Output: &out}).Apply(cmd, &u)
+	if flags.inits != 0 {
+		t.Errorf("Apply called Init() of the flags")
+	}
+	diff := out.String()
+	for _, s := range []string{"missing usage for flag: name\n", "extraneous usage flag: size\n", "missing usage for command: sub\n"} {
+		if !strings.Contains(diff, s) {
+			t.Errorf("missing %q in:\n%s", s, diff)
+		}
+	}
+	if strings.Contains(diff, "count") {
+		t.Errorf("unexpected difference for count:\n%s", diff)
+	}
+}
@@ -1,8 +1,10 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"melato.org/command/reflx"
 )
@@ -80,8 +82,100 @@ func (t *sliceValue) Set(s string) error {
 	return nil
 }
 
-func newSliceValue(field *reflect.StructField, value reflect.Value, parse reflx.ParseFunc) *sliceValue {
+func (t *sliceValue) unset() {
+	t.isSet = false
+}
+
+func newSliceValue(value reflect.Value, parse reflx.ParseFunc) *sliceValue {
 	v := sliceValue{Value: value, Parse: parse}
-	v.eval = reflect.New(field.Type.Elem()).Elem()
+	v.eval = reflect.New(value.Type().Elem()).Elem()
 	return &v
 }
+
+// optionalValue is the flag.Value of a pointer to a scalar.
+// The pointer is nil, unless the flag is set, so that a command can tell whether the flag was given.
+type optionalValue struct {
+	Value      reflect.Value // the pointer
+	Parse      reflx.ParseFunc
+	pType      reflect.Type // the type of the scalar
+	DefaultUse string
+}
+
+func (t *optionalValue) IsBoolFlag() bool {
+	return t.pType == reflect.TypeOf(false)
+}
+
+func (t *optionalValue) String() string {
+	if !t.Value.IsValid() || t.Value.IsNil() {
+		return t.DefaultUse
+	}
+	s := fmt.Sprint(t.Value.Elem())
+	if t.pType == reflect.TypeOf("") {
+		return quote(s)
+	}
+	return s
+}
+
+func (t *optionalValue) Set(s string) error {
+	v := reflect.New(t.pType)
+	err := t.Parse(v.Elem(), s)
+	if err != nil {
+		return err
+	}
+	t.Value.Set(v)
+	return nil
+}
+
+// mapValue is the flag.Value of a map.  Each flag value is a key=value pair, which is added to the map.
+// The first flag value replaces the map, so that the command line replaces any default entries.
+type mapValue struct {
+	Value      reflect.Value
+	ParseKey   reflx.ParseFunc
+	Parse      reflx.ParseFunc
+	DefaultUse string
+
+	isSet bool
+}
+
+func (t *mapValue) String() string {
+	s := fmt.Sprint(t.Value)
+	if s == "map[]" && t.DefaultUse != "" {
+		return t.DefaultUse
+	}
+	return s
+}
+
+func (t *mapValue) Set(s string) error {
+	k, v, err := SplitKeyValue(s)
+	if err != nil {
+		return err
+	}
+	mType := t.Value.Type()
+	key := reflect.New(mType.Key()).Elem()
+	if err := t.ParseKey(key, k); err != nil {
+		return err
+	}
+	value := reflect.New(mType.Elem()).Elem()
+	if err := t.Parse(value, v); err != nil {
+		return err
+	}
+	if !t.isSet {
+		t.Value.Set(reflect.MakeMap(mType))
+		t.isSet = true
+	}
+	t.Value.SetMapIndex(key, value)
+	return nil
+}
+
+func (t *mapValue) unset() {
+	t.isSet = false
+}
+
+// SplitKeyValue splits a key=value map flag value.
+func SplitKeyValue(s string) (string, string, error) {
+	k, v, found := strings.Cut(s, "=")
+	if !found {
+		return "", "", errors.New("expected key=value: " + s)
+	}
+	return k, v, nil
+}